@@ -5,60 +5,99 @@ import (
 )
 
 type Pattern struct {
-	pattern string
-	kind    rune
+	Pattern string
+	Kind    rune
 }
 
-func (p Pattern) Accept(ifi map[string]interface{}) (string, interface{}, error) {
+func (p Pattern) Accept(ifi map[string]interface{}) (string, interface{}, bool, error) {
 	var (
 		value interface{}
 		key   string
+		found bool
 	)
 	for k, v := range ifi {
-		if Match(p.pattern, k) {
-			key, value = k, v
-			break
+		if (!found || k < key) && Match(p.Pattern, k) {
+			key, value, found = k, v, true
 		}
 	}
-	if value == nil {
-		return "", nil, nil
+	if !found {
+		return "", nil, found, nil
 	}
-	err := acceptValue(p.kind, value)
+	err := acceptValue(p.Kind, value)
 	if err != nil {
-		err = fmt.Errorf("%s: %w", p.pattern, err)
+		err = fmt.Errorf("%s: %w", p.Pattern, err)
 	}
-	return key, value, err
+	return key, value, found, err
 }
 
 func (p Pattern) String() string {
-	return p.pattern
+	return "/" + p.Pattern + "/"
 }
 
 type Name struct {
-	label string
-	kind  rune
+	Label string
+	Kind  rune
 }
 
-func (n Name) Accept(ifi map[string]interface{}) (string, interface{}, error) {
-	value, ok := ifi[n.label]
+func (n Name) Accept(ifi map[string]interface{}) (string, interface{}, bool, error) {
+	value, ok := ifi[n.Label]
 	if !ok {
-		return "", nil, nil
+		return "", nil, ok, nil
 	}
-	err := acceptValue(n.kind, value)
+	err := acceptValue(n.Kind, value)
 	if err != nil {
-		err = fmt.Errorf("%s: %w", n.label, err)
+		err = fmt.Errorf("%s: %w", n.Label, err)
 	}
-	return n.label, value, err
+	return n.Label, value, ok, err
 }
 
 func (n Name) String() string {
-	return n.label
+	return formatKey(n.Label)
+}
+
+// Wildcard (*) accepts all the keys of a table and all the elements of an
+// array whose value is of Kind.
+type Wildcard struct {
+	Kind rune
+}
+
+// Accept gives the first key of ifi whose value is of the kind of the
+// Wildcard. Queries give all of them instead.
+func (w Wildcard) Accept(ifi map[string]interface{}) (string, interface{}, bool, error) {
+	var (
+		value interface{}
+		key   string
+		found bool
+	)
+	for k, v := range ifi {
+		if (!found || k < key) && acceptValue(w.Kind, v) == nil {
+			key, value, found = k, v, true
+		}
+	}
+	return key, value, found, nil
+}
+
+func (w Wildcard) String() string {
+	return "*"
+}
+
+// Globstar (**) accepts the current table and all the tables below it, at any
+// level.
+type Globstar struct{}
+
+func (g Globstar) Accept(ifi map[string]interface{}) (string, interface{}, bool, error) {
+	return "", nil, false, nil
+}
+
+func (g Globstar) String() string {
+	return "**"
 }
 
 func acceptValue(kind rune, value interface{}) error {
 	if kind == 0 {
 		return nil
 	}
+	value = normalize(value)
 	switch {
 	case kind == TokArray && !isArray(value):
 		return fmt.Errorf("array expected!")
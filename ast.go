@@ -0,0 +1,177 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Node is any element of a parsed query: Query, Queryset, Axis, the
+// accepters, selectors and matchers of a query and the function calls of its
+// expressions. The String method of a Node gives back the canonical text of
+// the query that Parse turns into the same Node.
+type Node interface {
+	fmt.Stringer
+}
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(Node) Visitor
+}
+
+// Walk traverses a query in depth-first order: the choices of a query come
+// first, then its selector, its matcher and finally the next query.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	switch n := node.(type) {
+	case Queryset:
+		for _, q := range n {
+			walkNode(v, q)
+		}
+	case Query:
+		for _, a := range n.Choices {
+			walkNode(v, a)
+		}
+		walkNode(v, n.Get)
+		walkNode(v, n.Match)
+		walkNode(v, n.Next)
+	case Axis:
+		walkNode(v, n.Match)
+		walkNode(v, n.Next)
+	case Infix:
+		walkNode(v, n.Left)
+		walkNode(v, n.Right)
+	case Expr:
+		if n.Call != nil {
+			Walk(v, *n.Call)
+		}
+	}
+	v.Visit(nil)
+}
+
+func walkNode(v Visitor, ifi interface{}) {
+	if n, ok := ifi.(Node); ok {
+		Walk(v, n)
+	}
+}
+
+type inspector func(Node) bool
+
+func (fn inspector) Visit(n Node) Visitor {
+	if fn(n) {
+		return fn
+	}
+	return nil
+}
+
+// Inspect traverses a query in depth-first order like Walk. It calls fn(node)
+// for each node and, if fn returns true, for each of its children, followed
+// by a call of fn(nil).
+func Inspect(node Node, fn func(Node) bool) {
+	Walk(inspector(fn), node)
+}
+
+// formatKey writes a key bare when the scanner gives it back as a single
+// literal in queries and in expressions. Otherwise the key is quoted.
+func formatKey(key string) string {
+	if key == "" {
+		return quoteString(key)
+	}
+	if _, ok := identifiers[key]; ok {
+		return quoteString(key)
+	}
+	first := rune(key[0])
+	switch {
+	case isLetter(first):
+		for _, r := range key {
+			if !isAlpha(r) {
+				return quoteString(key)
+			}
+		}
+	case isDigit(first):
+		if first == zero && len(key) > 1 {
+			return quoteString(key)
+		}
+		for _, r := range key {
+			if !isDigit(r) {
+				return quoteString(key)
+			}
+		}
+	default:
+		return quoteString(key)
+	}
+	return key
+}
+
+func formatKind(a Accepter) string {
+	switch kindOf(a) {
+	case TokValue:
+		return "%"
+	case TokRegular:
+		return "$"
+	case TokArray:
+		return "@"
+	default:
+		return ""
+	}
+}
+
+func formatValue(ifi interface{}, op rune) string {
+	switch v := ifi.(type) {
+	case []interface{}:
+		strs := make([]string, len(v))
+		for i := range v {
+			strs[i] = formatValue(v[i], op)
+		}
+		return "(" + strings.Join(strs, ", ") + ")"
+	case string:
+		if op == TokMatch {
+			return "/" + v + "/"
+		}
+		return quoteString(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatFloat(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return formatTime(v)
+	case Null:
+		return "null"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	str := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".e") {
+		str += ".0"
+	}
+	return str
+}
+
+func formatTime(w time.Time) string {
+	if y, m, d := w.Date(); y == 0 && m == time.January && d == 1 {
+		return w.Format("15:04:05.999999999")
+	}
+	if w.Location() == time.UTC && w.Equal(w.Truncate(24*time.Hour)) {
+		return w.Format("2006-01-02")
+	}
+	return w.Format("2006-01-02T15:04:05.999999999Z07:00")
+}
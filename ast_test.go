@@ -0,0 +1,95 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	data := []struct {
+		Input string
+		Want  string
+	}{
+		{Input: "foo", Want: "..foo"},
+		{Input: "/?[a-z]*/", Want: "../?[a-z]*/"},
+		{Input: "foo.bar", Want: "..foo.bar"},
+		{Input: "..!foo.(1234, /[a-z][a-z][a-z][a-z]/)", Want: "..!foo.(1234,/[a-z][a-z][a-z][a-z]/)"},
+		{Input: "..$(foo,bar).%bar:number", Want: "..$(foo,bar).%bar:number"},
+		{Input: "..@\"foo\":at(5)", Want: "..@foo:at(5)"},
+		{Input: "..@/[a-zA-Z]?*/:range(0, 10)", Want: "..@/[a-zA-Z]?*/:range(0,10)"},
+		{Input: "..@foo:range(, 10)", Want: "..@foo:range(0,10)"},
+		{Input: "..@foo:range(0,)", Want: "..@foo:range(0,)"},
+		{Input: "..@foo:range(2,)", Want: "..@foo:range(2,)"},
+		{Input: ".foo..bar[str]", Want: ".foo..bar[str]"},
+		{Input: "..$foo[str == 'value'].bar,$foo[int == 0x10].bar", Want: `..$foo[str == "value"].bar,..$foo[int == 16].bar`},
+		{Input: "..$foo[date == 2020-10-12 || time == 13:14:15.678].bar", Want: "..$foo[date == 2020-10-12 || time == 13:14:15.678].bar"},
+		{Input: "foo[(int > 0 && int < 9) || (bool == true && pattern ~= /test/)]", Want: "..foo[(int > 0 && int < 9) || bool == true && pattern ~= /test/]"},
+		{Input: "foo[dt == (2020-10-12 13:14:15Z, 2020-10-12T07:08:09.333+02:00)]", Want: "..foo[dt == (2020-10-12T13:14:15Z, 2020-10-12T07:08:09.333+02:00)]"},
+		{Input: "foo[pat ~= (/[a-z][0-9]*/, /[A-Z][a-z].???/)]", Want: "..foo[pat ~= (/[a-z][0-9]*/, /[A-Z][a-z].???/)]"},
+		{Input: "foo[opt == null].bar:null", Want: "..foo[opt == null].bar:null"},
+		{Input: "foo[f == 1e3 || f == -inf || f == 1.5]", Want: "..foo[f == 1000.0 || f == -inf || f == 1.5]"},
+		{Input: "foo[port.pow(2) < 4096 && name.length == 3]", Want: "..foo[port.pow(2) < 4096 && name.length == 3]"},
+		{Input: `%"tlsé\U0001F600".'true'."a b".007`, Want: `..%"tlsé😀"."true"."a b"."007"`},
+		{Input: "foo[\n\ta == 1 &&\n\tb == 2\n].bar,\n@baz", Want: "..foo[a == 1 && b == 2].bar,..@baz"},
+		{Input: `foo["007" == "a\"b\n"]`, Want: `..foo["007" == "a\"b\n"]`},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		str := q.(Node).String()
+		if str != d.Want {
+			t.Errorf("%s: string mismatched! want %s, got %s", d.Input, d.Want, str)
+			continue
+		}
+		other, err := Parse(str)
+		if err != nil {
+			t.Errorf("%s: fail to parse %s: %s", d.Input, str, err)
+			continue
+		}
+		if !reflect.DeepEqual(q, other) {
+			t.Errorf("%s: %s does not give back the same query", d.Input, str)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	q, err := Parse("..$foo[str == 'value' && size.pow(2) > 10].bar:first,@baz")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	var got []string
+	Inspect(q.(Node), func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		switch n.(type) {
+		case Queryset:
+			got = append(got, "queryset")
+		case Query:
+			got = append(got, "query")
+		case Name:
+			got = append(got, "name:"+n.String())
+		case First:
+			got = append(got, "first")
+		case Infix:
+			got = append(got, "infix")
+		case Expr:
+			got = append(got, "expr")
+		case Call:
+			got = append(got, "call:"+n.String())
+			return false
+		}
+		return true
+	})
+	want := []string{
+		"queryset",
+		"query", "name:foo", "infix", "expr", "expr", "call:pow(2)", "query", "name:bar", "first",
+		"query", "name:baz",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("nodes mismatched!\nwant %v\ngot  %v", want, got)
+	}
+}
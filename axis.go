@@ -0,0 +1,187 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Axis moves from the value found by the query it follows. With Depth set to
+// TokParent (^), it gives the table holding the value; with TokAncestor (..^),
+// all the tables holding it from the nearest one to the document. The arrays
+// in between are skipped. With TokSibling (~), it gives the other values of the
+// table or of the array holding the value. The values are kept when they match
+// Match (the ancestors and siblings without an option of Match do not match)
+// and Next, if any, is applied to them, eg:
+//
+//	..tls[cert < 2021-01-01]^.name
+//
+// gives the name of the tables whose tls certificate expires. As in XPath, the
+// axis gives a position once for all the values the query finds, so
+// ..cert..^ gives the document only once.
+type Axis struct {
+	Depth rune
+	Match Matcher
+	Next  Queryer
+}
+
+func (a Axis) Select(ifi interface{}) ([]Result, error) {
+	return collect(a, ifi, trail{})
+}
+
+func (a Axis) Each(ifi interface{}, fn func(Result) bool) error {
+	return each(a, ifi, fn)
+}
+
+func (a Axis) String() string {
+	var b strings.Builder
+	switch a.Depth {
+	case TokAncestor:
+		b.WriteString("..^")
+	case TokSibling:
+		b.WriteString("~")
+	default:
+		b.WriteString("^")
+	}
+	if a.Match != nil {
+		b.WriteString("[" + a.Match.String() + "]")
+	}
+	if a.Next != nil {
+		b.WriteString(fmt.Sprint(a.Next))
+	}
+	return b.String()
+}
+
+func (a Axis) head() Axis {
+	a.Next = nil
+	return a
+}
+
+// selectWith resolves the tables above or next to the current position of at
+// from the document being queried.
+func (a Axis) selectWith(at *trail) error {
+	segs := make([]Segment, len(at.segs))
+	copy(segs, at.segs)
+	defer func() {
+		at.segs = append(at.segs[:0], segs...)
+	}()
+	id := a.String()
+	if a.Depth == TokSibling {
+		return a.selectSiblings(id, segs, at)
+	}
+	for i := len(segs) - 1; i >= 0; i-- {
+		ifi, err := Path(segs[:i]).Resolve(at.root)
+		if err != nil {
+			return fmt.Errorf("query: can not move up from %s: %w", Path(segs), err)
+		}
+		if _, ok := normalize(ifi).(map[string]interface{}); !ok {
+			continue
+		}
+		if at.move(id, Path(segs[:i])) {
+			at.segs = append(at.segs[:0], segs[:i]...)
+			if err := a.selectFromValue(ifi, at); err != nil {
+				return err
+			}
+		}
+		if a.Depth == TokParent {
+			break
+		}
+	}
+	return nil
+}
+
+// selectSiblings gives the other keys of the table or the other elements of
+// the array holding the value at segs.
+func (a Axis) selectSiblings(id string, segs []Segment, at *trail) error {
+	if len(segs) == 0 {
+		return nil
+	}
+	var (
+		last = segs[len(segs)-1]
+		up   = Path(segs[: len(segs)-1 : len(segs)-1])
+	)
+	ifi, err := up.Resolve(at.root)
+	if err != nil {
+		return fmt.Errorf("query: can not move from %s: %w", Path(segs), err)
+	}
+	var (
+		next []Segment
+		vs   []interface{}
+	)
+	switch ifi := normalize(ifi).(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(ifi) {
+			if seg := Key(k); seg != last {
+				next, vs = append(next, seg), append(vs, ifi[k])
+			}
+		}
+	case []interface{}:
+		for j, v := range ifi {
+			if seg := Index(j); seg != last {
+				next, vs = append(next, seg), append(vs, v)
+			}
+		}
+	}
+	for i, seg := range next {
+		if !a.accept(vs[i]) || !at.move(id, append(up, seg)) {
+			continue
+		}
+		at.segs = append(append(at.segs[:0], up...), seg)
+		if err := a.selectFromValue(vs[i], at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accept reports whether Match and Next can be applied to the sibling ifi:
+// the values that are not tables do not match and the queries can not
+// descend into scalars.
+func (a Axis) accept(ifi interface{}) bool {
+	switch normalize(ifi).(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		return a.Match == nil
+	default:
+		_, ok := a.Next.(Query)
+		return a.Match == nil && !ok
+	}
+}
+
+func (a Axis) selectFromValue(ifi interface{}, at *trail) error {
+	if a.Match != nil {
+		tab, _ := normalize(ifi).(map[string]interface{})
+		ok, err := a.Match.Match(tab)
+		if a.Depth != TokParent && errors.Is(err, ErrNotFound) {
+			ok, err = false, nil
+		}
+		if at.tracing() {
+			at.note(Step{Kind: StepMatch, Node: a.Match.String(), Ok: ok, Err: err})
+		}
+		if !ok || err != nil {
+			return err
+		}
+	}
+	if a.Next == nil {
+		at.note(Step{Kind: StepResult, Value: ifi})
+		return at.send(makeResult(at.Path(), ifi))
+	}
+	return selectWith(a.Next, ifi, at)
+}
+
+// hasAxis reports whether q moves up in the document at some point.
+func hasAxis(q Queryer) bool {
+	n, ok := q.(Node)
+	if !ok {
+		return false
+	}
+	var found bool
+	Inspect(n, func(n Node) bool {
+		if _, ok := n.(Axis); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
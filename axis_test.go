@@ -0,0 +1,165 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSelectAxis(t *testing.T) {
+	data := []struct {
+		Input string
+		Paths []string
+		Want  []interface{}
+	}{
+		{
+			Input: "..cred[passwd == \"temp123!\"]^.addr",
+			Paths: []string{"client[0].addr", "client[2].addr"},
+			Want:  []interface{}{"10.10.0.1:10001", "10.10.0.3:10001"},
+		},
+		{
+			Input: "..reboot^",
+			Paths: []string{"servers.backup", "servers.prime"},
+			Want:  []interface{}{backup, prime},
+		},
+		{
+			Input: "..%reboot^[qn ^= \"backup\"].addr",
+			Paths: []string{"servers.backup.addr"},
+			Want:  []interface{}{"10.10.1.15:10015"},
+		},
+		{
+			Input: "..@groups:first^",
+			Paths: []string{"servers"},
+			Want:  []interface{}{doc["servers"]},
+		},
+		{
+			Input: "..user..^[tls == true].addr",
+			Paths: []string{"client[1].addr", "client[2].addr"},
+			Want:  []interface{}{"10.10.0.2:10001", "10.10.0.3:10001"},
+		},
+		{
+			Input: ".admin.name..^.%service",
+			Paths: []string{"service"},
+			Want:  []interface{}{"foobar"},
+		},
+		{
+			Input: ".admin.name^^.age",
+			Paths: []string{"age"},
+			Want:  []interface{}{int64(3600)},
+		},
+		{
+			Input: ".admin.name^^^",
+		},
+		{
+			Input: "..user..^[unknown]",
+		},
+		{
+			Input: "..user..^[rps > 10]",
+			Paths: []string{"client[1]", "client[2]"},
+			Want:  []interface{}{client2, client3},
+		},
+		{
+			Input: "..user..^.service",
+			Paths: []string{"service"},
+			Want:  []interface{}{"foobar"},
+		},
+		{
+			Input: "..reboot^^",
+			Paths: []string{"servers"},
+			Want:  []interface{}{doc["servers"]},
+		},
+		{
+			Input: ".servers.prime~",
+			Paths: []string{"servers.backup", "servers.groups"},
+			Want:  []interface{}{backup, doc["servers"].(map[string]interface{})["groups"]},
+		},
+		{
+			Input: ".servers.prime~.addr",
+			Paths: []string{"servers.backup.addr", "servers.groups[0].addr", "servers.groups[1].addr"},
+			Want:  []interface{}{"10.10.1.15:10015", "239.192.0.1:31001", "224.0.0.1:31001"},
+		},
+		{
+			Input: ".servers.prime.addr~",
+			Paths: []string{"servers.prime.qn", "servers.prime.reboot"},
+			Want:  []interface{}{"prime.foobar.org", true},
+		},
+		{
+			Input: ".servers.prime~[reboot == false]",
+			Paths: []string{"servers.backup"},
+			Want:  []interface{}{backup},
+		},
+		{
+			Input: ".servers.(prime,backup)~",
+			Paths: []string{"servers.backup", "servers.groups", "servers.prime"},
+			Want:  []interface{}{backup, doc["servers"].(map[string]interface{})["groups"], prime},
+		},
+		{
+			Input: "..cred[user == \"user1\"]^~[tls == true].addr",
+			Paths: []string{"client[1].addr", "client[2].addr"},
+			Want:  []interface{}{"10.10.0.2:10001", "10.10.0.3:10001"},
+		},
+		{
+			Input: ".service~",
+			Paths: []string{"admin", "age", "client", "instances", "servers"},
+			Want:  []interface{}{admin, int64(3600), doc["client"], doc["instances"], doc["servers"]},
+		},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		if str := q.(Node).String(); str != d.Input {
+			t.Errorf("%s: string mismatched! got %s", d.Input, str)
+		}
+		rs, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", d.Input, err)
+			continue
+		}
+		var (
+			paths []string
+			vs    []interface{}
+		)
+		for _, r := range rs {
+			paths = append(paths, r.Paths.String())
+			vs = append(vs, r.Value)
+		}
+		if !reflect.DeepEqual(d.Paths, paths) {
+			t.Errorf("%s: paths mismatched! want %v, got %v", d.Input, d.Paths, paths)
+		}
+		if !reflect.DeepEqual(d.Want, vs) {
+			t.Errorf("%s: results mismatched! want %v, got %v", d.Input, d.Want, vs)
+		}
+	}
+}
+
+func TestSelectAxisNotFound(t *testing.T) {
+	q, err := Parse("..user^[rps > 10]")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	if _, err := q.Select(doc); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected %s, got %v", ErrNotFound, err)
+	}
+}
+
+func TestParseAxisInvalid(t *testing.T) {
+	data := []string{
+		"^",
+		"..^",
+		"foo,^bar",
+		"foo^bar",
+		"foo^[bar",
+		"foo.^",
+		"~",
+		"foo~bar",
+		"foo.~",
+	}
+	for _, str := range data {
+		if _, err := Parse(str); err == nil {
+			t.Errorf("%q: expected error but query parsed", str)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoKey = errors.New("builder: no key given")
+
+// Kinds of values accepted by the keys of a query, given to Builder.Type.
+const (
+	Array = TokArray
+	Table = TokRegular
+	Value = TokValue
+)
+
+// Builder constructs a query without going through its textual form, eg:
+//
+//	query.One("dependency").Type(query.Array).Where(query.Eq("optional", false)).Then(query.Any("version"))
+//
+// gives the same query as Parse(".@dependency[optional == false]..version").
+// A Builder is a Queryer itself. Its methods never modify the Builder they
+// are called on.
+type Builder struct {
+	qs []Query
+}
+
+// One starts a query looking for the keys in the current table only.
+func One(keys ...string) Builder {
+	return newBuilder(TokLevelOne, keys)
+}
+
+// Any starts a query looking for the keys in the current table and in all
+// its descendants.
+func Any(keys ...string) Builder {
+	return newBuilder(TokLevelAny, keys)
+}
+
+// Greedy starts a query at the greedy level (..!) of the query language. The
+// greedy level is not implemented yet: such a query only looks for the keys in
+// the current table, like One.
+func Greedy(keys ...string) Builder {
+	return newBuilder(TokLevelGreedy, keys)
+}
+
+func newBuilder(depth rune, keys []string) Builder {
+	q := Query{Depth: depth}
+	for _, k := range keys {
+		q.Choices = append(q.Choices, Name{Label: k})
+	}
+	return Builder{qs: []Query{q}}
+}
+
+// Patterns adds the patterns to the keys of the last query of the Builder.
+func (b Builder) Patterns(pats ...string) Builder {
+	return b.update(func(q *Query) {
+		var kind rune
+		if len(q.Choices) > 0 {
+			kind = kindOf(q.Choices[0])
+		}
+		cs := make([]Accepter, 0, len(q.Choices)+len(pats))
+		cs = append(cs, q.Choices...)
+		for _, p := range pats {
+			cs = append(cs, Pattern{Pattern: p, Kind: kind})
+		}
+		q.Choices = cs
+	})
+}
+
+// Type sets the kind of values accepted by the keys of the last query of the
+// Builder: Array, Table or Value.
+func (b Builder) Type(kind rune) Builder {
+	return b.update(func(q *Query) {
+		cs := make([]Accepter, len(q.Choices))
+		for i, a := range q.Choices {
+			switch a := a.(type) {
+			case Name:
+				a.Kind = kind
+				cs[i] = a
+			case Pattern:
+				a.Kind = kind
+				cs[i] = a
+			case Wildcard:
+				a.Kind = kind
+				cs[i] = a
+			default:
+				cs[i] = a
+			}
+		}
+		q.Choices = cs
+	})
+}
+
+// Get sets the selector of the last query of the Builder.
+func (b Builder) Get(sel Selector) Builder {
+	return b.update(func(q *Query) {
+		q.Get = sel
+	})
+}
+
+// Where sets the predicate of the last query of the Builder. Predicates given
+// by successive calls are combined with And.
+func (b Builder) Where(m Matcher) Builder {
+	return b.update(func(q *Query) {
+		if q.Match == nil {
+			q.Match = m
+		} else {
+			q.Match = And(q.Match, m)
+		}
+	})
+}
+
+// Then appends next as subquery of the Builder.
+func (b Builder) Then(next Builder) Builder {
+	qs := make([]Query, 0, len(b.qs)+len(next.qs))
+	qs = append(qs, b.qs...)
+	return Builder{qs: append(qs, next.qs...)}
+}
+
+// Build gives the query constructed. It fails when a query has no key or
+// when a predicate calls an unknown function.
+func (b Builder) Build() (Queryer, error) {
+	var next Queryer
+	for i := len(b.qs) - 1; i >= 0; i-- {
+		q := b.qs[i]
+		if len(q.Choices) == 0 {
+			return nil, ErrNoKey
+		}
+		if err := checkCalls(q.Match); err != nil {
+			return nil, err
+		}
+		q.Next = next
+		next = q
+	}
+	if next == nil {
+		return nil, ErrNoKey
+	}
+	return next, nil
+}
+
+func (b Builder) Select(doc interface{}) ([]Result, error) {
+	q, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(doc)
+}
+
+func (b Builder) Each(doc interface{}, fn func(Result) bool) error {
+	q, err := b.Build()
+	if err != nil {
+		return err
+	}
+	return Each(q, doc, fn)
+}
+
+func (b Builder) String() string {
+	q, err := b.Build()
+	if err != nil {
+		return ""
+	}
+	return q.(Node).String()
+}
+
+// checkCalls reports the first call of m to a function that does not exist.
+func checkCalls(m Matcher) error {
+	node, ok := m.(Node)
+	if !ok {
+		return nil
+	}
+	var err error
+	Inspect(node, func(n Node) bool {
+		if c, ok := n.(Call); ok && c.fn == nil {
+			err = fmt.Errorf("builder: unknown function %q", c.Name)
+		}
+		return err == nil
+	})
+	return err
+}
+
+func (b Builder) update(fn func(*Query)) Builder {
+	if len(b.qs) == 0 {
+		return b
+	}
+	qs := make([]Query, len(b.qs))
+	copy(qs, b.qs)
+	fn(&qs[len(qs)-1])
+	return Builder{qs: qs}
+}
+
+func kindOf(a Accepter) rune {
+	switch a := a.(type) {
+	case Name:
+		return a.Kind
+	case Pattern:
+		return a.Kind
+	case Wildcard:
+		return a.Kind
+	default:
+		return 0
+	}
+}
+
+func Eq(option string, value interface{}) Expr {
+	return makeExpr(option, TokEqual, value)
+}
+
+func Ne(option string, value interface{}) Expr {
+	return makeExpr(option, TokNotEqual, value)
+}
+
+func Lt(option string, value interface{}) Expr {
+	return makeExpr(option, TokLesser, value)
+}
+
+func Le(option string, value interface{}) Expr {
+	return makeExpr(option, TokLessEq, value)
+}
+
+func Gt(option string, value interface{}) Expr {
+	return makeExpr(option, TokGreater, value)
+}
+
+func Ge(option string, value interface{}) Expr {
+	return makeExpr(option, TokGreatEq, value)
+}
+
+func StartsWith(option, str string) Expr {
+	return makeExpr(option, TokStartsWith, str)
+}
+
+func EndsWith(option, str string) Expr {
+	return makeExpr(option, TokEndsWith, str)
+}
+
+func Contains(option, str string) Expr {
+	return makeExpr(option, TokContains, str)
+}
+
+// Like matches the value of option against pattern, like the ~= operator.
+func Like(option, pattern string) Expr {
+	return makeExpr(option, TokMatch, pattern)
+}
+
+// In is true when the value of option is equal to one of values.
+func In(option string, values ...interface{}) Expr {
+	vs := make([]interface{}, len(values))
+	for i := range values {
+		vs[i] = builderValue(values[i])
+	}
+	return Expr{
+		Option: option,
+		Op:     TokEqual,
+		Value:  vs,
+	}
+}
+
+func Exists(option string) Has {
+	return Has{Option: option}
+}
+
+// Apply calls the function name with args on the value of the option before
+// comparing it.
+func (e Expr) Apply(name string, args ...interface{}) Expr {
+	var vs []interface{}
+	for _, a := range args {
+		vs = append(vs, builderValue(a))
+	}
+	c, _ := makeCall(name, vs)
+	e.Call = &c
+	return e
+}
+
+func And(ms ...Matcher) Matcher {
+	return makeInfix(TokAnd, ms)
+}
+
+func Or(ms ...Matcher) Matcher {
+	return makeInfix(TokOr, ms)
+}
+
+// makeInfix nests the matchers to the right, like the parser does.
+func makeInfix(op rune, ms []Matcher) Matcher {
+	switch len(ms) {
+	case 0:
+		return nil
+	case 1:
+		return ms[0]
+	default:
+		return Infix{
+			Left:  ms[0],
+			Right: makeInfix(op, ms[1:]),
+			Op:    op,
+		}
+	}
+}
+
+func makeExpr(option string, op rune, value interface{}) Expr {
+	return Expr{
+		Option: option,
+		Op:     op,
+		Value:  builderValue(value),
+	}
+}
+
+func builderValue(ifi interface{}) interface{} {
+	if ifi == nil {
+		return Null{}
+	}
+	return normalize(ifi)
+}
@@ -0,0 +1,63 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	data := []struct {
+		Input string
+		Builder
+	}{
+		{
+			Input:   ".@dependency[optional == false]..version",
+			Builder: One("dependency").Type(Array).Where(Eq("optional", false)).Then(Any("version")),
+		},
+		{
+			Input:   "..!$(foo,bar,/ba?/):first.%\"a key\":int",
+			Builder: Greedy("foo", "bar").Patterns("ba?").Type(Table).Get(First{}).Then(One("a key").Type(Value).Get(Int{})),
+		},
+		{
+			Input:   "@groups:range(1,)[mode && every > 10 || addr ~= /*:31001/]",
+			Builder: Any("groups").Type(Array).Get(Range{Start: 1}).Where(Exists("mode")).Where(Or(Gt("every", 10), Like("addr", "*:31001"))),
+		},
+		{
+			Input:   "client[port.pow(2) == (1, 2.5, null) && since < 2021-03-04]",
+			Builder: Any("client").Where(And(In("port", 1, float32(2.5), nil).Apply("pow", 2), Lt("since", time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)))),
+		},
+	}
+	for _, d := range data {
+		want, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		got, err := d.Build()
+		if err != nil {
+			t.Errorf("%s: fail to build: %s", d.Input, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: queries mismatched! got %s", d.Input, got)
+		}
+	}
+}
+
+func TestBuilderSelect(t *testing.T) {
+	b := One("servers").Then(One("groups").Type(Array).Where(Eq("mode", 255))).Then(One("addr"))
+	rs, err := b.Select(doc)
+	if err != nil {
+		t.Fatalf("fail to select: %s", err)
+	}
+	if len(rs) != 1 || rs[0].Value != "224.0.0.1:31001" {
+		t.Fatalf("results mismatched! got %v", rs)
+	}
+	if _, err := One().Build(); err == nil {
+		t.Fatalf("query without key should fail to build")
+	}
+	if _, err := One("client").Where(Eq("port", 1).Apply("unknown")).Build(); err == nil {
+		t.Fatalf("query calling unknown function should fail to build")
+	}
+}
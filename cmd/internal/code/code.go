@@ -5,3 +5,35 @@ const (
 	ExitBadDoc
 	ExitEmpty
 )
+
+// Merge gives the exit code of a command run over several files: a bad query
+// wins over a bad document and the results are only empty when all the files
+// give no results.
+func Merge(codes ...int) int {
+	var (
+		query, doc bool
+		ok, empty  bool
+	)
+	for _, c := range codes {
+		switch c {
+		case ExitBadQuery:
+			query = true
+		case ExitBadDoc:
+			doc = true
+		case ExitEmpty:
+			empty = true
+		default:
+			ok = true
+		}
+	}
+	switch {
+	case query:
+		return ExitBadQuery
+	case doc:
+		return ExitBadDoc
+	case empty && !ok:
+		return ExitEmpty
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midbel/query"
+	"github.com/midbel/query/cmd/internal/code"
+)
+
+const (
+	formatJSON = "json"
+	formatTOML = "toml"
+	formatYAML = "yaml"
+	formatCSV  = "csv"
+	formatTSV  = "tsv"
+
+	formatJSONL  = "jsonl"
+	formatNDJSON = "ndjson"
+)
+
+var encoders = map[string]func(io.Writer, map[string]interface{}) error{
+	formatJSON: encodeJSON,
+	formatTOML: encodeTOML,
+	formatYAML: encodeYAML,
+}
+
+func runConvert(args []string) {
+	var (
+		set   = flag.NewFlagSet("convert", flag.ExitOnError)
+		from  = set.String("from", "", "format of input document (toml, json, csv, tsv)")
+		to    = set.String("to", formatJSON, "format of output document (json, yaml, toml)")
+		expr  = set.String("q", "", "only convert the selection of the given query")
+		root  = set.String("r", query.DefaultRoot, "root key of rows in csv/tsv document")
+		infer = set.String("i", "", "infer types of columns (comma separated list or * for all) in csv/tsv document")
+		times = set.Bool("t", false, "convert strings holding a date and/or a time to datetime values")
+	)
+	set.Parse(args)
+
+	encode, ok := encoders[*to]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: unsupported output format\n", *to)
+		os.Exit(code.ExitBadDoc)
+	}
+	var q query.Queryer
+	if *expr != "" {
+		var err error
+		if q, err = query.Parse(*expr); err != nil {
+			fmt.Fprintln(os.Stderr, *expr, err)
+			os.Exit(code.ExitBadQuery)
+		}
+	}
+	opts := csvOptions{
+		Root:  *root,
+		Infer: *infer,
+	}
+	doc, err := decodeFormat(set.Arg(0), *from, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(code.ExitBadDoc)
+	}
+	if *times {
+		parseTimes(doc)
+	}
+	if q != nil {
+		rs, err := q.Select(doc)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(code.ExitBadQuery)
+		}
+		if len(rs) == 0 {
+			os.Exit(code.ExitEmpty)
+		}
+		doc = selectionDocument(rs)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	if err := encode(w, doc); err != nil {
+		w.Flush()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(code.ExitBadDoc)
+	}
+}
+
+func selectionDocument(rs []query.Result) map[string]interface{} {
+	if len(rs) == 1 {
+		if m, ok := rs[0].Value.(map[string]interface{}); ok && len(rs[0].Paths) == 0 {
+			return m
+		}
+	}
+	var (
+		doc  = make(map[string]interface{})
+		seen = make(map[string]int)
+	)
+	for _, r := range rs {
+		paths := r.Paths.Keys()
+		if len(paths) == 0 {
+			continue
+		}
+		var (
+			curr = doc
+			last = len(paths) - 1
+		)
+		for _, p := range paths[:last] {
+			next, ok := curr[p].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				curr[p] = next
+			}
+			curr = next
+		}
+		key := strings.Join(paths, ".")
+		switch seen[key] {
+		case 0:
+			curr[paths[last]] = r.Value
+		case 1:
+			curr[paths[last]] = []interface{}{curr[paths[last]], r.Value}
+		default:
+			curr[paths[last]] = append(curr[paths[last]].([]interface{}), r.Value)
+		}
+		seen[key]++
+	}
+	return doc
+}
+
+func parseTimes(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if w, err := parseDatetime(v); err == nil {
+			return w
+		}
+		if w, err := time.ParseInLocation(dateFormat, v, localDate); err == nil {
+			return w
+		}
+		if w, err := time.ParseInLocation(timeFormat, v, localTime); err == nil {
+			return w
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = parseTimes(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = parseTimes(v[k])
+		}
+	}
+	return value
+}
+
+func encodeJSON(w io.Writer, doc map[string]interface{}) error {
+	value, err := jsonValue(doc)
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	e.SetEscapeHTML(false)
+	return e.Encode(value)
+}
+
+func jsonValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case query.Null:
+		return nil, nil
+	case time.Time:
+		return formatTime(v), nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("json: %v can not be represented", v)
+		}
+		return json.Number(formatFloat(v)), nil
+	case []interface{}:
+		vs := make([]interface{}, len(v))
+		for i := range v {
+			x, err := jsonValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			vs[i] = x
+		}
+		return vs, nil
+	case map[string]interface{}:
+		vs := make(map[string]interface{}, len(v))
+		for k := range v {
+			x, err := jsonValue(v[k])
+			if err != nil {
+				return nil, err
+			}
+			vs[k] = x
+		}
+		return vs, nil
+	default:
+		return v, nil
+	}
+}
+
+func encodeYAML(w io.Writer, doc map[string]interface{}) error {
+	if len(doc) == 0 {
+		_, err := io.WriteString(w, "{}\n")
+		return err
+	}
+	return yamlTable(w, doc, 0)
+}
+
+func yamlTable(w io.Writer, doc map[string]interface{}, level int) error {
+	indent := strings.Repeat("  ", level)
+	for i, k := range sortedKeys(doc) {
+		if i > 0 {
+			io.WriteString(w, indent)
+		}
+		io.WriteString(w, yamlKey(k))
+		io.WriteString(w, ":")
+		if err := yamlNested(w, doc[k], level); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func yamlArray(w io.Writer, arr []interface{}, level int) error {
+	indent := strings.Repeat("  ", level)
+	for _, v := range arr {
+		io.WriteString(w, indent)
+		io.WriteString(w, "-")
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			io.WriteString(w, " ")
+			if err := yamlTable(w, m, level+1); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := yamlNested(w, v, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func yamlNested(w io.Writer, value interface{}, level int) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			io.WriteString(w, " {}\n")
+			return nil
+		}
+		io.WriteString(w, "\n")
+		io.WriteString(w, strings.Repeat("  ", level+1))
+		return yamlTable(w, v, level+1)
+	case []interface{}:
+		if len(v) == 0 {
+			io.WriteString(w, " []\n")
+			return nil
+		}
+		io.WriteString(w, "\n")
+		return yamlArray(w, v, level+1)
+	default:
+		str, err := yamlValue(v)
+		if err != nil {
+			return err
+		}
+		io.WriteString(w, " ")
+		io.WriteString(w, str)
+		io.WriteString(w, "\n")
+		return nil
+	}
+}
+
+func yamlKey(key string) string {
+	if isBare(key) && !isDigits(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func yamlValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil, query.Null:
+		return "null", nil
+	case string:
+		return strconv.Quote(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return ".nan", nil
+		case math.IsInf(v, 1):
+			return ".inf", nil
+		case math.IsInf(v, -1):
+			return "-.inf", nil
+		}
+		return formatFloat(v), nil
+	case time.Time:
+		str := formatTime(v)
+		if v.Location() == localTime {
+			str = strconv.Quote(str)
+		}
+		return str, nil
+	default:
+		return "", fmt.Errorf("yaml: unsupported type %T", value)
+	}
+}
+
+func encodeTOML(w io.Writer, doc map[string]interface{}) error {
+	return tomlTable(w, doc, nil, false)
+}
+
+func tomlTable(w io.Writer, doc map[string]interface{}, paths []string, array bool) error {
+	var (
+		keys   = sortedKeys(doc)
+		tables []string
+		arrays []string
+	)
+	if len(paths) > 0 {
+		header := tomlPath(paths)
+		if array {
+			fmt.Fprintf(w, "[[%s]]\n", header)
+		} else {
+			fmt.Fprintf(w, "[%s]\n", header)
+		}
+	}
+	for _, k := range keys {
+		switch v := doc[k].(type) {
+		case map[string]interface{}:
+			tables = append(tables, k)
+			continue
+		case []interface{}:
+			if isArrayOfTables(v) {
+				arrays = append(arrays, k)
+				continue
+			}
+		}
+		str, err := tomlValue(doc[k])
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		fmt.Fprintf(w, "%s = %s\n", tomlKey(k), str)
+	}
+	for _, k := range tables {
+		io.WriteString(w, "\n")
+		err := tomlTable(w, doc[k].(map[string]interface{}), append(paths[:len(paths):len(paths)], k), false)
+		if err != nil {
+			return err
+		}
+	}
+	for _, k := range arrays {
+		for _, v := range doc[k].([]interface{}) {
+			io.WriteString(w, "\n")
+			err := tomlTable(w, v.(map[string]interface{}), append(paths[:len(paths):len(paths)], k), true)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tomlValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil, query.Null:
+		return "", fmt.Errorf("toml: null can not be represented")
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "nan", nil
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		}
+		return formatFloat(v), nil
+	case time.Time:
+		return formatTime(v), nil
+	case []interface{}:
+		vs := make([]string, 0, len(v))
+		for _, i := range v {
+			str, err := tomlValue(i)
+			if err != nil {
+				return "", err
+			}
+			vs = append(vs, str)
+		}
+		return "[" + strings.Join(vs, ", ") + "]", nil
+	case map[string]interface{}:
+		vs := make([]string, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			str, err := tomlValue(v[k])
+			if err != nil {
+				return "", err
+			}
+			vs = append(vs, fmt.Sprintf("%s = %s", tomlKey(k), str))
+		}
+		return "{" + strings.Join(vs, ", ") + "}", nil
+	default:
+		return "", fmt.Errorf("toml: unsupported type %T", value)
+	}
+}
+
+func tomlPath(paths []string) string {
+	ps := make([]string, len(paths))
+	for i := range paths {
+		ps[i] = tomlKey(paths[i])
+	}
+	return strings.Join(ps, ".")
+}
+
+func tomlKey(key string) string {
+	if isBare(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlString(str string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '"':
+			b.WriteString("\\\"")
+		case '\\':
+			b.WriteString("\\\\")
+		case '\b':
+			b.WriteString("\\b")
+		case '\t':
+			b.WriteString("\\t")
+		case '\n':
+			b.WriteString("\\n")
+		case '\f':
+			b.WriteString("\\f")
+		case '\r':
+			b.WriteString("\\r")
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, "\\u%04X", r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func isArrayOfTables(arr []interface{}) bool {
+	if len(arr) == 0 {
+		return false
+	}
+	for _, v := range arr {
+		if _, ok := v.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isBare(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		ok := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9') || r == '_' || r == '-'
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(key string) bool {
+	for _, r := range key {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func formatFloat(f float64) string {
+	str := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eEn") {
+		str += ".0"
+	}
+	return str
+}
+
+func sortedKeys(doc map[string]interface{}) []string {
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
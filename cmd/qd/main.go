@@ -2,20 +2,45 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/midbel/query"
 	"github.com/midbel/query/cmd/internal/code"
-	"github.com/midbel/toml"
 )
 
 func main() {
-	kv := flag.Bool("k", false, "print key/value")
+	if len(os.Args) > 1 && os.Args[1] == "convert" {
+		runConvert(os.Args[2:])
+		return
+	}
+	var (
+		kv    = flag.Bool("k", false, "print key/value")
+		root  = flag.String("r", query.DefaultRoot, "root key of rows in csv/tsv document")
+		infer = flag.String("i", "", "infer types of columns (comma separated list or * for all) in csv/tsv document")
+		table = flag.String("table", "", "print results as table (csv, tsv, markdown, text)")
+		cols  = flag.String("columns", "", "comma separated list of columns to print with table")
+		expl  = flag.Bool("explain", false, "print the steps of the evaluation of the query")
+		how   = flag.String("explain-format", "tree", "print the steps of the evaluation as tree or json")
+		jobs  = flag.Int("j", runtime.NumCPU(), "number of files processed in parallel")
+		strm  = flag.Bool("stream", false, "evaluate the query while reading json documents instead of decoding them first")
+		split = flag.String("split", "", "query each of the toml/json documents of a file separated by lines holding only the given marker")
+		lines = flag.Bool("n", false, "prefix results with the line number of their record in jsonl/ndjson or split files")
+		skip  = flag.Bool("skip", false, "skip invalid records with a warning instead of failing")
+	)
 	flag.Parse()
+	if err := checkTable(*table); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(code.ExitBadQuery)
+	}
 
 	q, err := query.Parse(flag.Arg(0))
 	if err != nil {
@@ -23,75 +48,378 @@ func main() {
 		os.Exit(code.ExitBadQuery)
 	}
 
-	doc, err := decodeDocument(flag.Arg(1))
+	r := runner{
+		Query: q,
+		Options: csvOptions{
+			Root:  *root,
+			Infer: *infer,
+		},
+		Table:   *table,
+		Explain: *expl,
+		Format:  *how,
+		Stream:  *strm,
+		Split:   *split,
+		Lines:   *lines,
+		Skip:    *skip,
+		Print:   nokey,
+	}
+	if *cols != "" {
+		r.Columns = strings.Split(*cols, ",")
+	}
+	if *kv {
+		r.Print = withkey
+	}
+	files := flag.Args()[1:]
+	if len(files) == 0 {
+		files = append(files, "")
+	}
+	var exit int
+	if r.Table != "" && !r.Explain {
+		exit = r.runTable(os.Stdout, os.Stderr, files, *jobs)
+	} else {
+		exit = runFiles(os.Stdout, os.Stderr, files, *jobs, r.run)
+	}
+	if exit != 0 {
+		os.Exit(exit)
+	}
+}
+
+type runner struct {
+	Query   query.Queryer
+	Options csvOptions
+	Table   string
+	Columns []string
+	Explain bool
+	Format  string
+	Stream  bool
+	Split   string
+	Lines   bool
+	Skip    bool
+	Print   func(io.Writer, string, interface{})
+
+	rows *tableRows
+}
+
+// run evaluates the query against file and writes its output to w. It gives
+// the exit code of file.
+func (r runner) run(file string, w, warn io.Writer) (int, error) {
+	if format := formatOf(file); r.Split != "" || format == formatJSONL || format == formatNDJSON {
+		return r.runRecords(file, w, warn)
+	}
+	if r.Stream && !r.Explain && formatOf(file) == formatJSON {
+		return r.streamJSON(file, w)
+	}
+	doc, err := decodeDocument(file, r.Options)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(code.ExitBadDoc)
+		return code.ExitBadDoc, err
+	}
+	if r.Explain {
+		return runExplain(w, r.Query, doc, r.Format)
 	}
-	ifi, err := q.Select(doc)
+	ifi, err := r.Query.Select(doc)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(code.ExitBadQuery)
+		return code.ExitBadQuery, err
 	}
+	return r.write(file, w, ifi)
+}
+
+// write prints the results of file to w. In table mode, they are collected to
+// be printed with the ones of the other files if runTable is used.
+func (r runner) write(file string, w io.Writer, ifi []query.Result) (int, error) {
 	if len(ifi) == 0 {
-		os.Exit(code.ExitEmpty)
+		return code.ExitEmpty, nil
 	}
-	print := nokey
-	if *kv {
-		print = withkey
+	if r.rows != nil {
+		r.rows.add(file, ifi)
+		return 0, nil
+	}
+	if r.Table != "" {
+		t, err := makeTable(ifi, r.Columns)
+		if err == nil {
+			err = t.Write(w, r.Table)
+		}
+		if err != nil {
+			return code.ExitBadQuery, err
+		}
+		return 0, nil
 	}
-	printResults(ifi, print)
+	printResults(w, ifi, r.Print)
+	return 0, nil
 }
 
-const (
-	jsonExt = ".json"
-	tomlExt = ".toml"
-)
+// runRecords evaluates the query against each record of file. Invalid records
+// are reported to warn when they are skipped.
+func (r runner) runRecords(file string, w, warn io.Writer) (int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return code.ExitBadDoc, err
+	}
+	defer f.Close()
 
-func decodeDocument(file string) (map[string]interface{}, error) {
+	var rr *query.RecordReader
+	switch format := formatOf(file); {
+	case format == formatJSONL || format == formatNDJSON:
+		rr = query.NewJSONLinesReader(f)
+	case format == formatTOML:
+		rr = query.NewRecordReader(f, r.Split, decodeTOML)
+	case format == formatJSON:
+		rr = query.NewRecordReader(f, r.Split, decodeJSON)
+	default:
+		return code.ExitBadDoc, fmt.Errorf("%s: unsupported file type", format)
+	}
+	var (
+		all   []query.Result
+		codes []int
+		found bool
+	)
+	for {
+		rec, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var re *query.RecordError
+			if r.Skip && errors.As(err, &re) {
+				fmt.Fprintf(warn, "%s: %s (skipped)\n", file, err)
+				continue
+			}
+			return code.ExitBadDoc, err
+		}
+		if r.Explain {
+			c, err := runExplain(w, r.Query, rec.Doc, r.Format)
+			if err != nil {
+				return c, err
+			}
+			codes = append(codes, c)
+			continue
+		}
+		rs, err := r.Query.Select(rec.Doc)
+		if err != nil {
+			return code.ExitBadQuery, fmt.Errorf("line %d: %w", rec.Line, err)
+		}
+		if len(rs) == 0 {
+			continue
+		}
+		found = true
+		if r.Table != "" {
+			all = append(all, rs...)
+		} else {
+			printResults(w, rs, r.printLine(rec.Line))
+		}
+	}
+	switch {
+	case r.Explain:
+		return code.Merge(codes...), nil
+	case r.Table != "":
+		return r.write(file, w, all)
+	case !found:
+		return code.ExitEmpty, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (r runner) printLine(line int) func(io.Writer, string, interface{}) {
+	if !r.Lines {
+		return r.Print
+	}
+	return func(w io.Writer, key string, value interface{}) {
+		fmt.Fprintf(w, "%d:", line)
+		r.Print(w, key, value)
+	}
+}
+
+func runExplain(w io.Writer, q query.Queryer, doc interface{}, how string) (int, error) {
+	tr, _ := query.Explain(q, doc)
+	var err error
+	switch how {
+	case "tree", "":
+		err = tr.WriteTree(w)
+	case "json":
+		err = tr.WriteJSON(w)
+	default:
+		err = fmt.Errorf("%s: unsupported explain format", how)
+	}
+	switch {
+	case err != nil:
+		return code.ExitBadQuery, err
+	case tr.Err != nil:
+		return code.ExitBadQuery, nil
+	case len(tr.Results) == 0:
+		return code.ExitEmpty, nil
+	default:
+		return 0, nil
+	}
+}
+
+type csvOptions struct {
+	Root  string
+	Infer string
+}
+
+func (c csvOptions) decode(d *query.CSVDecoder) (map[string]interface{}, error) {
+	d.SetRoot(c.Root)
+	switch c.Infer {
+	case "":
+	case "*":
+		d.InferTypes()
+	default:
+		d.InferTypes(strings.Split(c.Infer, ",")...)
+	}
+	return d.Decode()
+}
+
+func decodeDocument(file string, opts csvOptions) (map[string]interface{}, error) {
+	return decodeFormat(file, "", opts)
+}
+
+func decodeFormat(file, format string, opts csvOptions) (map[string]interface{}, error) {
 	r, err := os.Open(file)
 	if err != nil {
 		return nil, err
 	}
 	defer r.Close()
 
-	var doc = make(map[string]interface{})
-	switch ext := filepath.Ext(file); strings.ToLower(ext) {
-	case tomlExt:
-		err = toml.Decode(r, &doc)
-	case jsonExt:
-		err = json.NewDecoder(r).Decode(&doc)
+	if format == "" {
+		format = formatOf(file)
+	}
+	var doc map[string]interface{}
+	switch format {
+	case formatTOML:
+		doc, err = decodeTOML(r)
+	case formatJSON:
+		doc, err = decodeJSON(r)
+	case formatCSV:
+		doc, err = opts.decode(query.NewCSVDecoder(r))
+	case formatTSV:
+		doc, err = opts.decode(query.NewTSVDecoder(r))
 	default:
-		err = fmt.Errorf("%s: unsupported file type", ext)
+		err = fmt.Errorf("%s: unsupported file type", format)
 	}
 	return doc, err
 }
 
-func nokey(_ string, value interface{}) {
-	fmt.Println(value)
+func formatOf(file string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+}
+
+// streamJSON evaluates the query while reading file and prints each result as
+// soon as it is found. The results are collected in table mode.
+func (r runner) streamJSON(file string, w io.Writer) (int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return code.ExitBadDoc, err
+	}
+	defer f.Close()
+
+	var (
+		rs    []query.Result
+		found bool
+	)
+	err = query.StreamJSON(r.Query, f, func(res query.Result) bool {
+		found = true
+		if r.Table != "" {
+			rs = append(rs, res)
+		} else {
+			printResult(w, res.Paths, res.Value, r.Print)
+		}
+		return true
+	})
+	switch {
+	case err != nil:
+		return streamCode(err), err
+	case r.Table != "":
+		return r.write(file, w, rs)
+	case !found:
+		return code.ExitEmpty, nil
+	default:
+		return 0, nil
+	}
+}
+
+// streamCode tells apart the errors of the document from the ones of the query
+// when both are evaluated at the same time.
+func streamCode(err error) int {
+	var (
+		syntax *json.SyntaxError
+		path   *os.PathError
+	)
+	if errors.As(err, &syntax) || errors.As(err, &path) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, query.ErrDuplicateKey) {
+		return code.ExitBadDoc
+	}
+	return code.ExitBadQuery
 }
 
-func withkey(key string, value interface{}) {
-	fmt.Printf("%s = %v\n", key, value)
+func decodeJSON(r io.Reader) (map[string]interface{}, error) {
+	var (
+		doc map[string]interface{}
+		dec = json.NewDecoder(r)
+	)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return convertJSON(doc).(map[string]interface{}), nil
 }
 
-func printResults(rs []query.Result, print func(string, interface{})) {
+func convertJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return query.Null{}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = convertJSON(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = convertJSON(v[k])
+		}
+	}
+	return value
+}
+
+func nokey(w io.Writer, _ string, value interface{}) {
+	fmt.Fprintln(w, printValue(value))
+}
+
+func withkey(w io.Writer, key string, value interface{}) {
+	fmt.Fprintf(w, "%s = %v\n", key, printValue(value))
+}
+
+func printValue(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return formatTime(t)
+	}
+	return value
+}
+
+func printResults(w io.Writer, rs []query.Result, print func(io.Writer, string, interface{})) {
 	for _, r := range rs {
-		printResult(strings.Join(r.Paths, "."), r.Value, print)
+		printResult(w, r.Paths, r.Value, print)
 	}
 }
 
-func printResult(key string, value interface{}, print func(string, interface{})) {
+func printResult(w io.Writer, key query.Path, value interface{}, print func(io.Writer, string, interface{})) {
 	switch ifi := value.(type) {
 	case []interface{}:
 		for j, i := range ifi {
-			printResult(fmt.Sprintf("%s.%d", key, j), i, print)
+			printResult(w, appendPath(key, query.Index(j)), i, print)
 		}
 	case map[string]interface{}:
 		for k, v := range ifi {
-			printResult(fmt.Sprintf("%s.%s", key, k), v, print)
+			printResult(w, appendPath(key, query.Key(k)), v, print)
 		}
 	default:
-		print(key, ifi)
+		print(w, key.String(), ifi)
 	}
 }
+
+func appendPath(key query.Path, seg query.Segment) query.Path {
+	return append(key[:len(key):len(key)], seg)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/midbel/query"
+	"github.com/midbel/query/cmd/internal/code"
+)
+
+type output struct {
+	file string
+	out  *orderedWriter
+	warn *orderedWriter
+	code int
+	err  error
+	done chan struct{}
+}
+
+// orderedWriter buffers what is written to it until the file it belongs to is
+// the next one to be printed. From then, it writes straight to its writer.
+type orderedWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	buf    bytes.Buffer
+	direct bool
+}
+
+func (o *orderedWriter) Write(b []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.direct {
+		return o.w.Write(b)
+	}
+	return o.buf.Write(b)
+}
+
+func (o *orderedWriter) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w.Write(o.buf.Bytes())
+	o.buf = bytes.Buffer{}
+	o.direct = true
+}
+
+// runFiles calls run on each file with at most jobs files processed at the
+// same time. The output of the files is written to w in the order of files:
+// the file to be printed next writes straight to w, the ones that are ahead
+// of it are buffered until its turn. Their warnings and errors are reported
+// on warn without stopping the other files. It gives the exit code of all
+// the files merged.
+func runFiles(w, warn io.Writer, files []string, jobs int, run func(string, io.Writer, io.Writer) (int, error)) int {
+	if jobs <= 0 {
+		jobs = 1
+	}
+	var (
+		outs  = make([]*output, len(files))
+		sema  = make(chan struct{}, jobs)
+		codes = make([]int, 0, len(files))
+	)
+	for i := range files {
+		outs[i] = &output{
+			file: files[i],
+			out:  &orderedWriter{w: w},
+			warn: &orderedWriter{w: warn},
+			done: make(chan struct{}),
+		}
+	}
+	go func() {
+		for _, o := range outs {
+			sema <- struct{}{}
+			go func(o *output) {
+				defer close(o.done)
+				o.code, o.err = run(o.file, o.out, o.warn)
+			}(o)
+		}
+	}()
+	for _, o := range outs {
+		o.out.flush()
+		o.warn.flush()
+		<-o.done
+		if o.err != nil {
+			fmt.Fprintf(warn, "%s: %s\n", o.file, o.err)
+		}
+		codes = append(codes, o.code)
+		<-sema
+	}
+	return code.Merge(codes...)
+}
+
+// tableRows collects the results of the files printed as a single table.
+type tableRows struct {
+	mu    sync.Mutex
+	files map[string][]query.Result
+}
+
+// add records the results of file. A file given twice is queried twice with
+// the same results, so the last ones replace the first.
+func (t *tableRows) add(file string, rs []query.Result) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files[file] = rs
+}
+
+// runTable evaluates the query against files like runFiles and writes all
+// their results to w as one table, its rows following the order of files.
+func (r runner) runTable(w, warn io.Writer, files []string, jobs int) int {
+	r.rows = &tableRows{
+		files: make(map[string][]query.Result),
+	}
+	exit := runFiles(w, warn, files, jobs, r.run)
+	if exit == code.ExitEmpty {
+		return exit
+	}
+	var all []query.Result
+	for _, f := range files {
+		all = append(all, r.rows.files[f]...)
+	}
+	if len(all) == 0 {
+		return exit
+	}
+	t, err := makeTable(all, r.Columns)
+	if err == nil {
+		err = t.Write(w, r.Table)
+	}
+	if err != nil {
+		fmt.Fprintln(warn, err)
+		return code.ExitBadQuery
+	}
+	return exit
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/midbel/query"
+	"github.com/midbel/query/cmd/internal/code"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(b)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestRunFilesOrder(t *testing.T) {
+	var (
+		out, warn syncBuffer
+		done      = make(chan struct{})
+		direct    bool
+	)
+	run := func(file string, w, _ io.Writer) (int, error) {
+		switch file {
+		case "b":
+			io.WriteString(w, "b\n")
+			close(done)
+			return code.ExitEmpty, nil
+		case "a":
+			<-done
+			io.WriteString(w, "a\n")
+			for i := 0; i < 100 && !direct; i++ {
+				direct = out.String() == "a\n"
+				time.Sleep(time.Millisecond * 10)
+			}
+		}
+		return 0, nil
+	}
+	exit := runFiles(&out, &warn, []string{"a", "b"}, 2, run)
+	if exit != 0 {
+		t.Errorf("exit code mismatched! want 0, got %d", exit)
+	}
+	if !direct {
+		t.Errorf("output of the first file not written before it is done")
+	}
+	if got := out.String(); got != "a\nb\n" {
+		t.Errorf("output mismatched! want %q, got %q", "a\nb\n", got)
+	}
+}
+
+func TestRunTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	docs := []string{
+		`{"a": [{"x": 1, "y": 2}]}`,
+		`{"a": [{"x": 3, "z": 4}]}`,
+		`{"b": 1}`,
+	}
+	var files []string
+	for i, d := range docs {
+		file := filepath.Join(dir, string(rune('1'+i))+".json")
+		if err := ioutil.WriteFile(file, []byte(d), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	q, err := query.Parse(".a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := runner{
+		Query: q,
+		Table: tableCSV,
+		Print: nokey,
+	}
+	var out, warn syncBuffer
+	if exit := r.runTable(&out, &warn, append(files, files[0]), 2); exit != 0 {
+		t.Errorf("exit code mismatched! want 0, got %d", exit)
+	}
+	want := "x,y,z\n1,2,\n3,,4\n1,2,\n"
+	if got := out.String(); got != want {
+		t.Errorf("table mismatched! want %q, got %q", want, got)
+	}
+	out = syncBuffer{}
+	if exit := r.runTable(&out, &warn, files[2:], 2); exit != code.ExitEmpty {
+		t.Errorf("exit code mismatched! want %d, got %d", code.ExitEmpty, exit)
+	}
+	if got := out.String(); got != "" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/midbel/query"
+)
+
+const (
+	tableCSV      = "csv"
+	tableTSV      = "tsv"
+	tableMarkdown = "markdown"
+	tableText     = "text"
+)
+
+type table struct {
+	columns []string
+	rows    [][]string
+}
+
+func makeTable(rs []query.Result, columns []string) (table, error) {
+	var ms []map[string]interface{}
+	for _, r := range rs {
+		var err error
+		if ms, err = appendRows(ms, r.Value); err != nil {
+			return table{}, fmt.Errorf("%s: %w", r.Paths, err)
+		}
+	}
+	if len(columns) == 0 {
+		columns = unionKeys(ms)
+	}
+	t := table{
+		columns: columns,
+		rows:    make([][]string, 0, len(ms)),
+	}
+	for _, m := range ms {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			if v, ok := m[c]; ok {
+				row[i] = formatCell(v)
+			}
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t, nil
+}
+
+func appendRows(ms []map[string]interface{}, value interface{}) ([]map[string]interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		ms = append(ms, v)
+	case []interface{}:
+		for _, i := range v {
+			m, ok := i.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s can not be printed as a row", formatValue(i))
+			}
+			ms = append(ms, m)
+		}
+	default:
+		return nil, fmt.Errorf("%s can not be printed as a row", formatValue(v))
+	}
+	return ms, nil
+}
+
+func unionKeys(ms []map[string]interface{}) []string {
+	var (
+		keys []string
+		seen = make(map[string]struct{})
+	)
+	for _, m := range ms {
+		for k := range m {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func checkTable(format string) error {
+	switch format {
+	case "", tableCSV, tableTSV, tableMarkdown, tableText:
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported table format", format)
+	}
+}
+
+func (t table) Write(w io.Writer, format string) error {
+	switch format {
+	case tableCSV:
+		return t.writeCSV(w, ',')
+	case tableTSV:
+		return t.writeCSV(w, '\t')
+	case tableMarkdown:
+		return t.writeMarkdown(w)
+	case tableText:
+		return t.writeText(w)
+	default:
+		return fmt.Errorf("%s: unsupported table format", format)
+	}
+}
+
+func (t table) writeCSV(w io.Writer, sep rune) error {
+	ws := csv.NewWriter(w)
+	ws.Comma = sep
+	ws.Write(t.columns)
+	for _, r := range t.rows {
+		ws.Write(r)
+	}
+	ws.Flush()
+	return ws.Error()
+}
+
+func (t table) writeMarkdown(w io.Writer) error {
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	line := func(cells []string) {
+		io.WriteString(w, "|")
+		for _, c := range cells {
+			io.WriteString(w, " ")
+			io.WriteString(w, escape.Replace(c))
+			io.WriteString(w, " |")
+		}
+		io.WriteString(w, "\n")
+	}
+	line(t.columns)
+	sep := make([]string, len(t.columns))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	line(sep)
+	for _, r := range t.rows {
+		line(r)
+	}
+	return nil
+}
+
+func (t table) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.columns, "\t"))
+	for _, r := range t.rows {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+	return tw.Flush()
+}
+
+func formatCell(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return formatValue(value)
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case query.Null:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return formatTime(v)
+	case []interface{}:
+		vs := make([]string, 0, len(v))
+		for _, i := range v {
+			vs = append(vs, formatValue(i))
+		}
+		return "[" + strings.Join(vs, ", ") + "]"
+	case map[string]interface{}:
+		ks := make([]string, 0, len(v))
+		for k := range v {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		vs := make([]string, 0, len(v))
+		for _, k := range ks {
+			vs = append(vs, fmt.Sprintf("%s = %s", k, formatValue(v[k])))
+		}
+		return "{" + strings.Join(vs, ", ") + "}"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
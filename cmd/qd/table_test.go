@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midbel/query"
+)
+
+func TestMakeTable(t *testing.T) {
+	rs := []query.Result{
+		{
+			Paths: query.Path{query.Key("changelog"), query.Index(0)},
+			Value: map[string]interface{}{
+				"version": "0.1.0",
+				"date":    time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			Paths: query.Path{query.Key("changelog")},
+			Value: []interface{}{
+				map[string]interface{}{"version": "0.2.0", "breaking": true},
+			},
+		},
+	}
+	tab, err := makeTable(rs, nil)
+	if err != nil {
+		t.Fatalf("fail to make table: %s", err)
+	}
+	want := table{
+		columns: []string{"breaking", "date", "version"},
+		rows: [][]string{
+			{"", "2020-10-01T00:00:00Z", "0.1.0"},
+			{"true", "", "0.2.0"},
+		},
+	}
+	if !reflect.DeepEqual(want, tab) {
+		t.Errorf("table mismatched! want %v, got %v", want, tab)
+	}
+
+	tab, err = makeTable(rs, []string{"version", "author"})
+	if err != nil {
+		t.Fatalf("fail to make table: %s", err)
+	}
+	want = table{
+		columns: []string{"version", "author"},
+		rows:    [][]string{{"0.1.0", ""}, {"0.2.0", ""}},
+	}
+	if !reflect.DeepEqual(want, tab) {
+		t.Errorf("table mismatched! want %v, got %v", want, tab)
+	}
+}
+
+func TestMakeTableInvalid(t *testing.T) {
+	data := []interface{}{
+		"0.1.0",
+		int64(42),
+		[]interface{}{map[string]interface{}{"version": "0.1.0"}, "0.2.0"},
+	}
+	for _, v := range data {
+		rs := []query.Result{{Paths: query.Path{query.Key("version")}, Value: v}}
+		if _, err := makeTable(rs, nil); err == nil {
+			t.Errorf("%v: expected error but table made", v)
+		}
+	}
+}
+
+func TestFormatCell(t *testing.T) {
+	data := []struct {
+		Value interface{}
+		Want  string
+	}{
+		{Value: "foo bar", Want: "foo bar"},
+		{Value: int64(-42), Want: "-42"},
+		{Value: 0.25, Want: "0.25"},
+		{Value: 1e21, Want: "1000000000000000000000"},
+		{Value: true, Want: "true"},
+		{Value: query.Null{}, Want: "null"},
+		{Value: time.Date(2020, 10, 1, 12, 30, 0, 0, time.UTC), Want: "2020-10-01T12:30:00Z"},
+		{Value: []interface{}{"a", int64(1)}, Want: `["a", 1]`},
+		{Value: map[string]interface{}{"b": "x", "a": []interface{}{}}, Want: `{a = [], b = "x"}`},
+	}
+	for _, d := range data {
+		if got := formatCell(d.Value); got != d.Want {
+			t.Errorf("%v: cell mismatched! want %s, got %s", d.Value, d.Want, got)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	tab := table{
+		columns: []string{"name", "note"},
+		rows:    [][]string{{"foo", "a|b"}, {"bar", ""}},
+	}
+	data := []struct {
+		Format string
+		Want   string
+	}{
+		{Format: tableCSV, Want: "name,note\nfoo,a|b\nbar,\n"},
+		{Format: tableTSV, Want: "name\tnote\nfoo\ta|b\nbar\t\n"},
+		{Format: tableMarkdown, Want: "| name | note |\n| --- | --- |\n| foo | a\\|b |\n| bar |  |\n"},
+		{Format: tableText, Want: "name  note\nfoo   a|b\nbar   \n"},
+	}
+	for _, d := range data {
+		var b strings.Builder
+		if err := tab.Write(&b, d.Format); err != nil {
+			t.Errorf("%s: fail to write table: %s", d.Format, err)
+			continue
+		}
+		if got := b.String(); got != d.Want {
+			t.Errorf("%s: table mismatched! want %q, got %q", d.Format, d.Want, got)
+		}
+	}
+	if err := checkTable("html"); err == nil {
+		t.Errorf("html: expected error but format accepted")
+	}
+}
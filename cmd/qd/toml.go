@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/midbel/toml"
+)
+
+var (
+	localDatetime = time.FixedZone("local-datetime", 0)
+	localDate     = time.FixedZone("local-date", 0)
+	localTime     = time.FixedZone("local-time", 0)
+	zeroOffset    = time.FixedZone("+00:00", 0)
+)
+
+const (
+	dateFormat = "2006-01-02"
+	timeFormat = "15:04:05.999999999"
+)
+
+func formatTime(t time.Time) string {
+	switch t.Location() {
+	case localDatetime:
+		return t.Format(dateFormat + "T" + timeFormat)
+	case localDate:
+		return t.Format(dateFormat)
+	case localTime:
+		return t.Format(timeFormat)
+	case zeroOffset:
+		return t.Format(dateFormat + "T" + timeFormat + "-07:00")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// decodeTOML decodes a toml document with toml.Decode keeping the kind of its
+// datetimes (offset datetime, local datetime, local date or local time) so
+// that convert can write them back as they were. toml.Decode drops the local
+// times and gives the local datetimes in UTC: the datetimes are given to it
+// as strings holding their index and replaced by their value afterwards.
+func decodeTOML(r io.Reader) (map[string]interface{}, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	buf = bytes.ReplaceAll(buf, []byte("\r\n"), []byte("\n"))
+	marker := timeMarker
+	for bytes.Contains(buf, []byte(marker)) {
+		marker += timeMarker
+	}
+	src, times, err := markTimes(buf, marker)
+	if err != nil {
+		return nil, err
+	}
+	doc := make(map[string]interface{})
+	if err := toml.Decode(bytes.NewReader(src), &doc); err != nil {
+		return nil, err
+	}
+	restoreTimes(doc, marker, times)
+	return doc, nil
+}
+
+// timeMarker starts the strings replacing the datetimes. It is repeated until
+// it is not found in the document.
+const timeMarker = "\uE000"
+
+// markTimes replaces the datetimes of buf by a literal string made of marker
+// and their index in the list of their values.
+func markTimes(buf []byte, marker string) ([]byte, []time.Time, error) {
+	scan, err := toml.NewScanner(bytes.NewReader(buf))
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		src   bytes.Buffer
+		times []time.Time
+		lines = lineOffsets(buf)
+		last  int
+		fail  error
+	)
+	for tok := scan.Scan(); tok.Type != toml.TokEOF; tok = scan.Scan() {
+		var (
+			when time.Time
+			err  error
+		)
+		switch tok.Type {
+		case toml.TokDatetime:
+			when, err = parseDatetime(tok.Literal)
+		case toml.TokDate:
+			when, err = time.ParseInLocation(dateFormat, tok.Literal, localDate)
+		case toml.TokTime:
+			when, err = time.ParseInLocation(timeFormat, tok.Literal, localTime)
+		default:
+			continue
+		}
+		if fail != nil {
+			continue
+		}
+		if err != nil {
+			fail = err
+			continue
+		}
+		at := offsetOf(buf, lines, tok.Pos)
+		if at < last || !bytes.HasPrefix(buf[at:], []byte(tok.Raw)) {
+			fail = fmt.Errorf("toml(%s): can not locate %s", tok.Pos, tok.Raw)
+			continue
+		}
+		src.Write(buf[last:at])
+		src.WriteString("'" + marker + strconv.Itoa(len(times)) + "'")
+		times = append(times, when)
+		last = at + len(tok.Raw)
+	}
+	if fail != nil {
+		return nil, nil, fail
+	}
+	src.Write(buf[last:])
+	return src.Bytes(), times, nil
+}
+
+// lineOffsets gives the offset of the start of each line of buf. As counted
+// by toml.Scanner, a line starts with the newline ending the previous one.
+func lineOffsets(buf []byte) []int {
+	lines := []int{0}
+	for i, b := range buf {
+		if b == '\n' {
+			lines = append(lines, i)
+		}
+	}
+	return lines
+}
+
+func offsetOf(buf []byte, lines []int, pos toml.Position) int {
+	if pos.Line < 1 || pos.Line > len(lines) {
+		return -1
+	}
+	at := lines[pos.Line-1]
+	for i := 1; i < pos.Column && at < len(buf); i++ {
+		_, z := utf8.DecodeRune(buf[at:])
+		at += z
+	}
+	return at
+}
+
+// restoreTimes replaces the strings set by markTimes by their datetime.
+func restoreTimes(ifi interface{}, marker string, times []time.Time) interface{} {
+	switch v := ifi.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = restoreTimes(v[k], marker, times)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = restoreTimes(v[i], marker, times)
+		}
+	case string:
+		if !strings.HasPrefix(v, marker) {
+			break
+		}
+		if i, err := strconv.Atoi(v[len(marker):]); err == nil && i < len(times) {
+			return times[i]
+		}
+	}
+	return ifi
+}
+
+func parseDatetime(str string) (time.Time, error) {
+	str = strings.Replace(str, " ", "T", 1)
+	if w, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		if _, offset := w.Zone(); offset == 0 && !strings.HasSuffix(strings.ToUpper(str), "Z") {
+			w = w.In(zeroOffset)
+		}
+		return w, nil
+	}
+	return time.ParseInLocation(dateFormat+"T"+timeFormat, str, localDatetime)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const servers = `[[srv]]
+name = "alpha"
+
+[srv.tls]
+cert = "alpha.pem"
+
+[srv.tls.options]
+strict = true
+
+[[srv]]
+name = "beta"
+
+[srv.tls]
+cert = "beta.pem"
+
+[srv.tls.options]
+strict = false
+`
+
+func TestDecodeTOMLArrayTables(t *testing.T) {
+	doc, err := decodeTOML(strings.NewReader(servers))
+	if err != nil {
+		t.Fatalf("fail to decode toml: %s", err)
+	}
+	arr, ok := doc["srv"].([]interface{})
+	if !ok || len(arr) != 2 {
+		t.Fatalf("srv: array of 2 tables expected, got %v", doc["srv"])
+	}
+	for i, cert := range []string{"alpha.pem", "beta.pem"} {
+		tls, _ := arr[i].(map[string]interface{})["tls"].(map[string]interface{})
+		if tls["cert"] != cert {
+			t.Errorf("srv[%d].tls.cert: want %s, got %v", i, cert, tls["cert"])
+		}
+	}
+}
+
+func TestDecodeTOMLInvalid(t *testing.T) {
+	data := []string{
+		"[a]\nb = 1\n[a]\nc = 2\n",
+		"[[srv]]\n[srv.tls]\n[srv.tls]\n",
+		"[a]\nb = 1\n[[a]]\n",
+		"a = 1\na = 2\n",
+	}
+	for _, str := range data {
+		if _, err := decodeTOML(strings.NewReader(str)); err == nil {
+			t.Errorf("%q: expected error but document decoded", str)
+		}
+	}
+}
+
+func TestConvertTOMLTimes(t *testing.T) {
+	data := []string{
+		"1979-05-27T07:32:00Z",
+		"1979-05-27T07:32:00+00:00",
+		"1979-05-27T00:32:00.999999-07:00",
+		"1979-05-27T07:32:00",
+		"1979-05-27",
+		"07:32:00",
+	}
+	for _, str := range data {
+		doc, err := decodeTOML(strings.NewReader("when = " + str + "\n"))
+		if err != nil {
+			t.Errorf("%s: fail to decode toml: %s", str, err)
+			continue
+		}
+		var b strings.Builder
+		if err := encodeTOML(&b, doc); err != nil {
+			t.Errorf("%s: fail to encode toml: %s", str, err)
+			continue
+		}
+		if want := "when = " + str + "\n"; b.String() != want {
+			t.Errorf("%s: toml mismatched! want %q, got %q", str, want, b.String())
+		}
+		b.Reset()
+		if err := encodeJSON(&b, doc); err != nil {
+			t.Errorf("%s: fail to encode json: %s", str, err)
+			continue
+		}
+		if want := "{\n  \"when\": \"" + str + "\"\n}\n"; b.String() != want {
+			t.Errorf("%s: json mismatched! want %q, got %q", str, want, b.String())
+		}
+	}
+}
+
+func TestDecodeTOMLTimes(t *testing.T) {
+	const doc = "note = \"1979-05-27 \"\r\n" +
+		"at = {name = \"éàü\", when = 07:32:00}\r\n" +
+		"[[srv]]\n" +
+		"dates = [1979-05-27, # first\n  1979-05-27T07:32:00Z]\n"
+	got, err := decodeTOML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("fail to decode toml: %s", err)
+	}
+	if got["note"] != "1979-05-27 " {
+		t.Errorf("note: string mismatched! got %v", got["note"])
+	}
+	at, _ := got["at"].(map[string]interface{})
+	if w, ok := at["when"].(time.Time); !ok || formatTime(w) != "07:32:00" {
+		t.Errorf("at.when: local time expected, got %v", at["when"])
+	}
+	srv, _ := got["srv"].([]interface{})
+	if len(srv) != 1 {
+		t.Fatalf("srv: array of 1 table expected, got %v", got["srv"])
+	}
+	dates, _ := srv[0].(map[string]interface{})["dates"].([]interface{})
+	for i, want := range []string{"1979-05-27", "1979-05-27T07:32:00Z"} {
+		if i >= len(dates) {
+			t.Errorf("srv[0].dates[%d]: missing value", i)
+			continue
+		}
+		if w, ok := dates[i].(time.Time); !ok || formatTime(w) != want {
+			t.Errorf("srv[0].dates[%d]: want %s, got %v", i, want, dates[i])
+		}
+	}
+}
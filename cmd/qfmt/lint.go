@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/midbel/query"
+)
+
+func lint(q query.Queryer) []string {
+	node, ok := q.(query.Node)
+	if !ok {
+		return nil
+	}
+	var msgs []string
+	query.Inspect(node, func(n query.Node) bool {
+		switch n := n.(type) {
+		case query.Queryset:
+			seen := make(map[string]struct{})
+			for _, q := range n {
+				str := fmt.Sprint(q)
+				if _, ok := seen[str]; ok {
+					msgs = append(msgs, fmt.Sprintf("duplicate query %s in queryset", str))
+				}
+				seen[str] = struct{}{}
+			}
+		case query.Query:
+			seen := make(map[string]struct{})
+			for _, a := range n.Choices {
+				str := a.String()
+				if _, ok := seen[str]; ok {
+					msgs = append(msgs, fmt.Sprintf("duplicate key %s in choices", str))
+				}
+				seen[str] = struct{}{}
+			}
+		case query.Range:
+			switch {
+			case n.Start == 0 && n.End == 0:
+				msgs = append(msgs, fmt.Sprintf("%s selects every element", n))
+			case n.End != 0 && n.Start >= n.End:
+				msgs = append(msgs, fmt.Sprintf("%s selects no element", n))
+			case n.Start == 0 && n.End == 1:
+				msgs = append(msgs, fmt.Sprintf("%s can be written :first", n))
+			case n.End == n.Start+1:
+				msgs = append(msgs, fmt.Sprintf("%s can be written :at(%d)", n, n.Start))
+			}
+		case query.Infix:
+			if n.Left.String() == n.Right.String() {
+				msgs = append(msgs, fmt.Sprintf("same operand on both sides of %s", n))
+			}
+		case query.Expr:
+			vs, ok := n.Value.([]interface{})
+			if !ok {
+				break
+			}
+			for i := range vs {
+				for j := 0; j < i; j++ {
+					if reflect.DeepEqual(vs[i], vs[j]) {
+						msgs = append(msgs, fmt.Sprintf("duplicate value %v in %s", vs[i], n))
+						break
+					}
+				}
+			}
+		}
+		return true
+	})
+	return msgs
+}
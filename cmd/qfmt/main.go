@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/midbel/query"
+	"github.com/midbel/query/cmd/internal/code"
+)
+
+func main() {
+	var (
+		write = flag.Bool("w", false, "write result to file instead of stdout")
+		list  = flag.Bool("l", false, "list files whose formatting differs")
+		width = flag.Int("width", 80, "reflow queries longer than width")
+	)
+	flag.Parse()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = append(files, "")
+	}
+	var exit int
+	for _, file := range files {
+		buf, err := readFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit = code.ExitBadQuery
+			continue
+		}
+		if file == "" {
+			file = "<stdin>"
+		}
+		str, err := format(file, string(buf), *width)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+			exit = code.ExitBadQuery
+			continue
+		}
+		changed := str != string(buf)
+		if *list && changed {
+			fmt.Println(file)
+		}
+		if *write && file != "<stdin>" {
+			if changed {
+				err = ioutil.WriteFile(file, []byte(str), 0644)
+			}
+		} else if !*list {
+			_, err = os.Stdout.WriteString(str)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit = code.ExitBadQuery
+		}
+	}
+	os.Exit(exit)
+}
+
+func readFile(file string) ([]byte, error) {
+	if file == "" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(file)
+}
+
+func format(file, str string, width int) (string, error) {
+	q, err := query.Parse(strings.TrimSpace(str))
+	if err != nil {
+		return "", err
+	}
+	for _, msg := range lint(q) {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", file, msg)
+	}
+	return reflow(q, width) + "\n", nil
+}
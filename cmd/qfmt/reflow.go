@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/midbel/query"
+)
+
+func reflow(q query.Queryer, width int) string {
+	str := fmt.Sprint(q)
+	if len(str) <= width {
+		return str
+	}
+	qs, ok := q.(query.Queryset)
+	if !ok {
+		qs = query.Queryset{q}
+	}
+	var b strings.Builder
+	for i, q := range qs {
+		if i > 0 {
+			b.WriteString(",\n")
+		}
+		str := fmt.Sprint(q)
+		if len(str) <= width {
+			b.WriteString(str)
+			continue
+		}
+		reflowQuery(&b, q)
+	}
+	return b.String()
+}
+
+func reflowQuery(b *strings.Builder, q query.Queryer) {
+	for q != nil {
+		if a, ok := q.(query.Axis); ok {
+			head := query.Axis{Depth: a.Depth}
+			b.WriteString(head.String())
+			if a.Match != nil {
+				reflowMatcher(b, a.Match)
+			}
+			q = a.Next
+			continue
+		}
+		curr, ok := q.(query.Query)
+		if !ok {
+			b.WriteString(fmt.Sprint(q))
+			break
+		}
+		head := query.Query{
+			Choices: curr.Choices,
+			Depth:   curr.Depth,
+			Get:     curr.Get,
+		}
+		b.WriteString(head.String())
+		if curr.Match != nil {
+			reflowMatcher(b, curr.Match)
+		}
+		q = curr.Next
+	}
+}
+
+func reflowMatcher(b *strings.Builder, m query.Matcher) {
+	if _, ok := m.(query.Infix); !ok {
+		b.WriteString("[" + m.String() + "]")
+		return
+	}
+	b.WriteString("[\n")
+	for {
+		in, ok := m.(query.Infix)
+		if !ok {
+			break
+		}
+		left := in.Left.String()
+		if _, ok := in.Left.(query.Infix); ok {
+			left = "(" + left + ")"
+		}
+		op := "&&"
+		if in.Op == query.TokOr {
+			op = "||"
+		}
+		fmt.Fprintf(b, "\t%s %s\n", left, op)
+		m = in.Right
+	}
+	fmt.Fprintf(b, "\t%s\n]", m)
+}
@@ -18,6 +18,8 @@ func debug(q Queryer, out *bufio.Writer, level int) {
 	switch qs := q.(type) {
 	case Query:
 		debugQuery(qs, out, level)
+	case Axis:
+		debugAxis(qs, out, level)
 	case Queryset:
 		out.WriteString("queryset[\n")
 		for _, q := range qs {
@@ -46,23 +48,36 @@ func debugQuery(q Query, out *bufio.Writer, level int) {
 		out.WriteString(",\n")
 	}
 	write("query {", true)
-	writeKV("depth  = ", debugDepth(q.depth))
-	ks := make([]string, 0, len(q.choices))
-	for _, a := range q.choices {
+	writeKV("depth  = ", debugDepth(q.Depth))
+	ks := make([]string, 0, len(q.Choices))
+	for _, a := range q.Choices {
 		ks = append(ks, debugAccepter(a))
 	}
 	writeKV("keys   = ", strings.Join(ks, ", "))
-	writeKV("select = ", debugSelector(q.get))
+	writeKV("select = ", debugSelector(q.Get))
 
-	if q.match != nil {
-		writeKV("expr   = ", debugMatcher(q.match))
+	if q.Match != nil {
+		writeKV("expr   = ", debugMatcher(q.Match))
 	}
-	if q.next != nil {
-		debug(q.next, out, level+2)
+	if q.Next != nil {
+		debug(q.Next, out, level+2)
 	}
 	write("}", true)
 }
 
+func debugAxis(a Axis, out *bufio.Writer, level int) {
+	space := strings.Repeat(" ", level)
+	out.WriteString(space + "axis {\n")
+	out.WriteString(space + "  depth  = " + debugDepth(a.Depth) + ",\n")
+	if a.Match != nil {
+		out.WriteString(space + "  expr   = " + debugMatcher(a.Match) + ",\n")
+	}
+	if a.Next != nil {
+		debug(a.Next, out, level+2)
+	}
+	out.WriteString(space + "}\n")
+}
+
 func debugMatcher(m Matcher) string {
 	switch e := m.(type) {
 	default:
@@ -72,14 +87,13 @@ func debugMatcher(m Matcher) string {
 	case Infix:
 		return debugInfix(e)
 	case Has:
-		return fmt.Sprintf("exist(%s)", e.option)
+		return fmt.Sprintf("exist(%s)", e.Option)
 	}
-	return "matcher"
 }
 
 func debugExpr(e Expr) string {
 	var op string
-	switch e.op {
+	switch e.Op {
 	case TokEqual:
 		op = "eq"
 	case TokNotEqual:
@@ -113,12 +127,14 @@ func debugExpr(e Expr) string {
 			return fmt.Sprintf("string(%s)", v)
 		case time.Time:
 			return fmt.Sprintf("datetime(%s)", v.Format(time.RFC3339))
+		case Null:
+			return "null"
 		default:
 			return fmt.Sprintf("unknown(%v)", v)
 		}
 	}
 	var vs []string
-	switch es := e.value.(type) {
+	switch es := e.Value.(type) {
 	case []interface{}:
 		for _, e := range es {
 			vs = append(vs, valuetype(e))
@@ -126,16 +142,16 @@ func debugExpr(e Expr) string {
 	default:
 		vs = append(vs, valuetype(es))
 	}
-	return fmt.Sprintf("%s(option: %s, values: [%s])", op, e.option, strings.Join(vs, ", "))
+	return fmt.Sprintf("%s(option: %s, values: [%s])", op, e.Option, strings.Join(vs, ", "))
 }
 
 func debugInfix(e Infix) string {
 	var (
 		op    string
-		left  = debugMatcher(e.left)
-		right = debugMatcher(e.right)
+		left  = debugMatcher(e.Left)
+		right = debugMatcher(e.Right)
 	)
-	switch e.op {
+	switch e.Op {
 	case TokAnd:
 		op = "and"
 	case TokOr:
@@ -154,6 +170,12 @@ func debugDepth(depth rune) string {
 		return "any"
 	case TokLevelGreedy:
 		return "greedy"
+	case TokParent:
+		return "parent"
+	case TokAncestor:
+		return "ancestor"
+	case TokSibling:
+		return "sibling"
 	default:
 		return "unknown"
 	}
@@ -169,10 +191,16 @@ func debugAccepter(a Accepter) string {
 	switch a := a.(type) {
 	case Pattern:
 		str = "pattern"
-		label, typ = a.pattern, a.kind
+		label, typ = a.Pattern, a.Kind
 	case Name:
 		str = "label"
-		label, typ = a.label, a.kind
+		label, typ = a.Label, a.Kind
+	case Wildcard:
+		str = "wildcard"
+		label, typ = a.String(), a.Kind
+	case Globstar:
+		str = "globstar"
+		label = a.String()
 	}
 	switch typ {
 	case TokArray:
@@ -190,9 +218,9 @@ func debugAccepter(a Accepter) string {
 func debugSelector(sel Selector) string {
 	switch s := sel.(type) {
 	case At:
-		return fmt.Sprintf(":at(index: %d)", s.index)
+		return fmt.Sprintf(":at(index: %d)", s.Index)
 	case Range:
-		return fmt.Sprintf(":range(start: %d, end: %d)", s.start, s.end)
+		return fmt.Sprintf(":range(start: %d, end: %d)", s.Start, s.End)
 	case First:
 		return ":first"
 	case Last:
@@ -209,6 +237,14 @@ func debugSelector(sel Selector) string {
 		return ":truthy"
 	case Falsy:
 		return ":falsy"
+	case IsNull:
+		return ":null"
+	case Keys:
+		return ":keys"
+	case Values:
+		return ":values"
+	case Entries:
+		return ":entries"
 	default:
 		return ":all"
 	}
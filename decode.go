@@ -0,0 +1,246 @@
+package query
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const DefaultRoot = "rows"
+
+type CSVDecoder struct {
+	reader *csv.Reader
+	root   string
+	all    bool
+	infer  map[string]struct{}
+}
+
+func NewCSVDecoder(r io.Reader) *CSVDecoder {
+	return newDecoder(r, comma)
+}
+
+func NewTSVDecoder(r io.Reader) *CSVDecoder {
+	return newDecoder(r, tab)
+}
+
+func newDecoder(r io.Reader, sep rune) *CSVDecoder {
+	var d CSVDecoder
+	d.reader = csv.NewReader(r)
+	d.reader.Comma = sep
+	d.reader.ReuseRecord = true
+	d.root = DefaultRoot
+	d.infer = make(map[string]struct{})
+	return &d
+}
+
+func (d *CSVDecoder) SetRoot(root string) {
+	d.root = root
+}
+
+// InferTypes gives a single type to each of cols, or to all the columns when
+// cols is empty, chosen from all of their cells when the document is decoded.
+// Empty cells of typed columns are null.
+func (d *CSVDecoder) InferTypes(cols ...string) {
+	if len(cols) == 0 {
+		d.all = true
+	}
+	for _, c := range cols {
+		d.infer[c] = struct{}{}
+	}
+}
+
+func (d *CSVDecoder) Decode() (map[string]interface{}, error) {
+	head, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkInfer(head); err != nil {
+		return nil, err
+	}
+	var recs [][]string
+	for {
+		rec, err := d.reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, append([]string(nil), rec...))
+	}
+	kinds := make([]cellKind, len(head))
+	for i := range head {
+		kinds[i] = kindString
+		if d.shouldInfer(head[i]) {
+			kinds[i] = inferColumn(recs, i)
+		}
+	}
+	rows := make([]interface{}, 0, len(recs))
+	for _, rec := range recs {
+		row := make(map[string]interface{}, len(head))
+		for i, str := range rec {
+			row[head[i]] = kinds[i].convert(str)
+		}
+		rows = append(rows, row)
+	}
+	doc := map[string]interface{}{
+		d.root: rows,
+	}
+	return doc, nil
+}
+
+func (d *CSVDecoder) readHeader() ([]string, error) {
+	rec, err := d.reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf("csv: missing header")
+		}
+		return nil, err
+	}
+	var (
+		head = make([]string, len(rec))
+		seen = make(map[string]struct{})
+	)
+	for i, str := range rec {
+		if str == "" {
+			return nil, fmt.Errorf("csv: empty column name at position %d", i+1)
+		}
+		if _, ok := seen[str]; ok {
+			return nil, fmt.Errorf("csv: duplicate column %q", str)
+		}
+		seen[str] = struct{}{}
+		head[i] = str
+	}
+	return head, nil
+}
+
+func (d *CSVDecoder) checkInfer(head []string) error {
+	seen := make(map[string]struct{}, len(head))
+	for _, h := range head {
+		seen[h] = struct{}{}
+	}
+	for c := range d.infer {
+		if _, ok := seen[c]; !ok {
+			return fmt.Errorf("csv: unknown column %q", c)
+		}
+	}
+	return nil
+}
+
+func (d *CSVDecoder) shouldInfer(col string) bool {
+	if d.all {
+		return true
+	}
+	_, ok := d.infer[col]
+	return ok
+}
+
+type cellKind int
+
+const (
+	kindEmpty cellKind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindTime
+	kindString
+)
+
+// inferColumn gives the type of the values of column i that all of its cells
+// can be converted to. Integers and floats mixed give floats, everything else
+// mixed gives strings. The empty cells have no type.
+func inferColumn(recs [][]string, i int) cellKind {
+	kind := kindEmpty
+	for _, rec := range recs {
+		k := inferKind(rec[i])
+		switch {
+		case k == kindEmpty || k == kind:
+		case kind == kindEmpty:
+			kind = k
+		case (k == kindInt && kind == kindFloat) || (k == kindFloat && kind == kindInt):
+			kind = kindFloat
+		default:
+			return kindString
+		}
+	}
+	return kind
+}
+
+var datefmt = []string{
+	"2006-01-02",
+	time.RFC3339Nano,
+}
+
+func inferKind(str string) cellKind {
+	switch str {
+	case "":
+		return kindEmpty
+	case "true", "false":
+		return kindBool
+	}
+	if !isDigit(rune(str[0])) && !isSign(rune(str[0])) && str[0] != dot {
+		return kindString
+	}
+	if _, err := strconv.ParseInt(str, 10, 64); err == nil {
+		if hasLeadingZero(str) {
+			return kindString
+		}
+		return kindInt
+	}
+	if _, err := strconv.ParseFloat(str, 64); err == nil {
+		if hasLeadingZero(str) {
+			return kindString
+		}
+		return kindFloat
+	}
+	if _, ok := inferTime(str); ok {
+		return kindTime
+	}
+	return kindString
+}
+
+// hasLeadingZero reports whether str is a number written with a leading zero
+// (eg: 01234) that would be lost once parsed, like the codes.
+func hasLeadingZero(str string) bool {
+	if str[0] == '-' || str[0] == '+' {
+		str = str[1:]
+	}
+	return len(str) > 1 && str[0] == '0' && isDigit(rune(str[1]))
+}
+
+func inferTime(str string) (time.Time, bool) {
+	for _, ds := range [][]string{datefmt, datestr, timestr} {
+		for _, f := range ds {
+			if w, err := time.Parse(f, str); err == nil {
+				return w, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (k cellKind) convert(str string) interface{} {
+	if str == "" {
+		if k == kindString {
+			return str
+		}
+		return Null{}
+	}
+	switch k {
+	case kindBool:
+		return str == "true"
+	case kindInt:
+		i, _ := strconv.ParseInt(str, 10, 64)
+		return i
+	case kindFloat:
+		f, _ := strconv.ParseFloat(str, 64)
+		return f
+	case kindTime:
+		w, _ := inferTime(str)
+		return w
+	default:
+		return str
+	}
+}
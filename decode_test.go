@@ -0,0 +1,124 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const inventory = `host,cpu,ratio,region,active,since
+web1,8,1.5,eu,true,2020-10-01
+web2,2,0.5,us,false,2020-09-12T10:00:00Z
+db1,16,,eu,true,
+`
+
+func TestDecodeCSV(t *testing.T) {
+	d := NewCSVDecoder(strings.NewReader(inventory))
+	d.InferTypes()
+	doc, err := d.Decode()
+	if err != nil {
+		t.Fatalf("fail to decode csv: %s", err)
+	}
+	want := []interface{}{
+		map[string]interface{}{
+			"host":   "web1",
+			"cpu":    int64(8),
+			"ratio":  1.5,
+			"region": "eu",
+			"active": true,
+			"since":  time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		map[string]interface{}{
+			"host":   "web2",
+			"cpu":    int64(2),
+			"ratio":  0.5,
+			"region": "us",
+			"active": false,
+			"since":  time.Date(2020, 9, 12, 10, 0, 0, 0, time.UTC),
+		},
+		map[string]interface{}{
+			"host":   "db1",
+			"cpu":    int64(16),
+			"ratio":  Null{},
+			"region": "eu",
+			"active": true,
+			"since":  Null{},
+		},
+	}
+	if got := doc[DefaultRoot]; !reflect.DeepEqual(want, got) {
+		t.Errorf("rows mismatched!")
+		t.Logf("\twant: %v", want)
+		t.Logf("\tgot:  %v", got)
+	}
+
+	q, err := Parse(".rows[cpu > 4 && region == \"eu\"].host")
+	if err != nil {
+		t.Fatalf("fail to parse query: %s", err)
+	}
+	rs, err := q.Select(doc)
+	if err != nil {
+		t.Fatalf("fail to select rows: %s", err)
+	}
+	if len(rs) != 2 || rs[0].Value != "web1" || rs[1].Value != "db1" {
+		t.Errorf("unexpected results: %v", rs)
+	}
+}
+
+func TestDecodeTSV(t *testing.T) {
+	str := "name\tcount\nfoo\t10\nbar\t20\n"
+	d := NewTSVDecoder(strings.NewReader(str))
+	d.SetRoot("items")
+	d.InferTypes("count")
+	doc, err := d.Decode()
+	if err != nil {
+		t.Fatalf("fail to decode tsv: %s", err)
+	}
+	want := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "foo", "count": int64(10)},
+			map[string]interface{}{"name": "bar", "count": int64(20)},
+		},
+	}
+	if !reflect.DeepEqual(want, doc) {
+		t.Errorf("document mismatched! want %v, got %v", want, doc)
+	}
+}
+
+func TestDecodeCSVColumns(t *testing.T) {
+	str := "code,size,flag,note\n01234,1,true,\n00042,2.5,yes,foo\n,3,false,\n"
+	d := NewCSVDecoder(strings.NewReader(str))
+	d.InferTypes()
+	doc, err := d.Decode()
+	if err != nil {
+		t.Fatalf("fail to decode csv: %s", err)
+	}
+	want := []interface{}{
+		map[string]interface{}{"code": "01234", "size": 1.0, "flag": "true", "note": ""},
+		map[string]interface{}{"code": "00042", "size": 2.5, "flag": "yes", "note": "foo"},
+		map[string]interface{}{"code": "", "size": 3.0, "flag": "false", "note": ""},
+	}
+	if got := doc[DefaultRoot]; !reflect.DeepEqual(want, got) {
+		t.Errorf("rows mismatched! want %v, got %v", want, got)
+	}
+}
+
+func TestDecodeCSVErrors(t *testing.T) {
+	data := []string{
+		"",
+		"a,a\n1,2\n",
+		"a,,c\n1,2,3\n",
+		"a,b\n1,2,3\n",
+	}
+	for _, str := range data {
+		_, err := NewCSVDecoder(strings.NewReader(str)).Decode()
+		if err == nil {
+			t.Errorf("%q: expected error but got none", str)
+		}
+	}
+	d := NewCSVDecoder(strings.NewReader(inventory))
+	d.InferTypes("cpu", "memory")
+	if _, err := d.Decode(); err == nil {
+		t.Errorf("unknown column: expected error but got none")
+	}
+}
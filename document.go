@@ -0,0 +1,159 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Document wraps a decoded document to query it with typed getters. Queries
+// are parsed once and kept for the lifetime of the Document.
+//
+// Getters taking a default return it when their query gives no result. Without
+// default, they fail with ErrMissing. Getters for a single value fail with
+// ErrAmbiguous when their query gives more than one result and with a
+// CastError when the value found can not be converted.
+type Document struct {
+	doc interface{}
+
+	mu      sync.Mutex
+	queries map[string]Queryer
+}
+
+func NewDocument(doc interface{}) *Document {
+	return &Document{
+		doc:     doc,
+		queries: make(map[string]Queryer),
+	}
+}
+
+func (d *Document) Select(query string) ([]Result, error) {
+	q, err := d.parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(d.doc)
+}
+
+func (d *Document) Exists(query string) (bool, error) {
+	q, err := d.parse(query)
+	if err != nil {
+		return false, err
+	}
+	var found bool
+	err = Each(q, d.doc, func(_ Result) bool {
+		found = true
+		return false
+	})
+	return found, err
+}
+
+func (d *Document) Count(query string) (int, error) {
+	rs, err := d.Select(query)
+	return len(rs), err
+}
+
+func (d *Document) First(query string, def ...interface{}) (interface{}, error) {
+	rs, err := d.Select(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		if len(def) > 0 {
+			return def[0], nil
+		}
+		return nil, fmt.Errorf("%s: %w", query, ErrMissing)
+	}
+	return rs[0].Value, nil
+}
+
+func (d *Document) GetString(query string, def ...string) (string, error) {
+	var str string
+	if len(def) > 0 {
+		str = def[0]
+	}
+	return str, d.get(query, &str, len(def) > 0)
+}
+
+func (d *Document) GetInt(query string, def ...int64) (int64, error) {
+	var i int64
+	if len(def) > 0 {
+		i = def[0]
+	}
+	return i, d.get(query, &i, len(def) > 0)
+}
+
+func (d *Document) GetTime(query string, def ...time.Time) (time.Time, error) {
+	var w time.Time
+	if len(def) > 0 {
+		w = def[0]
+	}
+	return w, d.get(query, &w, len(def) > 0)
+}
+
+func (d *Document) GetStrings(query string, def ...[]string) ([]string, error) {
+	var strs []string
+	if len(def) > 0 {
+		strs = def[0]
+	}
+	rs, err := d.Select(query)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch len(rs) {
+	case 0:
+		if len(def) > 0 {
+			return strs, nil
+		}
+		return nil, fmt.Errorf("%s: %w", query, ErrMissing)
+	case 1:
+		value = rs[0].Value
+	default:
+		vs := make([]interface{}, len(rs))
+		for i := range rs {
+			vs[i] = rs[i].Value
+		}
+		value = vs
+	}
+	if err := loadValue(value, reflect.ValueOf(&strs).Elem(), query, nil); err != nil {
+		return nil, fmt.Errorf("%s: %w", query, err)
+	}
+	return strs, nil
+}
+
+func (d *Document) get(query string, v interface{}, optional bool) error {
+	rs, err := d.Select(query)
+	if err != nil {
+		return err
+	}
+	switch len(rs) {
+	case 0:
+		if optional {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", query, ErrMissing)
+	case 1:
+	default:
+		return fmt.Errorf("%s: %w (%d results)", query, ErrAmbiguous, len(rs))
+	}
+	if err := loadValue(rs[0].Value, reflect.ValueOf(v).Elem(), query, nil); err != nil {
+		return fmt.Errorf("%s: %w", query, err)
+	}
+	return nil
+}
+
+func (d *Document) parse(query string) (Queryer, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if q, ok := d.queries[query]; ok {
+		return q, nil
+	}
+	q, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	d.queries[query] = q
+	return q, nil
+}
@@ -0,0 +1,77 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDocument(t *testing.T) {
+	d := NewDocument(doc)
+
+	str, err := d.GetString(".servers.prime.qn")
+	if err != nil || str != "prime.foobar.org" {
+		t.Errorf("GetString: unexpected result %q (%v)", str, err)
+	}
+	if str, err = d.GetString(".missing", "default"); err != nil || str != "default" {
+		t.Errorf("GetString: default not used %q (%v)", str, err)
+	}
+	if _, err = d.GetString(".missing"); !errors.Is(err, ErrMissing) {
+		t.Errorf("GetString: expected ErrMissing, got %v", err)
+	}
+	if _, err = d.GetString("..addr"); !errors.Is(err, ErrAmbiguous) {
+		t.Errorf("GetString: expected ErrAmbiguous, got %v", err)
+	}
+	var cast CastError
+	if _, err = d.GetInt(".servers.prime.qn"); !errors.As(err, &cast) {
+		t.Errorf("GetInt: expected CastError, got %v", err)
+	}
+	if _, err = d.GetString("[bad"); err == nil {
+		t.Errorf("GetString: expected parse error")
+	}
+
+	n, err := d.GetInt("@groups:first.every")
+	if err != nil || n != 60 {
+		t.Errorf("GetInt: unexpected result %d (%v)", n, err)
+	}
+	if n, err = d.GetInt(".missing", 42); err != nil || n != 42 {
+		t.Errorf("GetInt: default not used %d (%v)", n, err)
+	}
+
+	w, err := d.GetTime("..dob")
+	if want := admin["dob"]; err != nil || w != want {
+		t.Errorf("GetTime: unexpected result %s (%v)", w, err)
+	}
+	def := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	if w, err = d.GetTime(".missing", def); err != nil || !w.Equal(def) {
+		t.Errorf("GetTime: default not used %s (%v)", w, err)
+	}
+
+	strs, err := d.GetStrings(".client.cred.user")
+	if want := []string{"user1", "user2", "user3"}; err != nil || !reflect.DeepEqual(want, strs) {
+		t.Errorf("GetStrings: unexpected result %v (%v)", strs, err)
+	}
+	if strs, err = d.GetStrings(".missing", []string{"a", "b"}); err != nil || len(strs) != 2 {
+		t.Errorf("GetStrings: default not used %v (%v)", strs, err)
+	}
+
+	if ok, err := d.Exists(".servers.backup"); !ok || err != nil {
+		t.Errorf("Exists: servers.backup should exist (%v)", err)
+	}
+	if ok, err := d.Exists(".servers.missing"); ok || err != nil {
+		t.Errorf("Exists: servers.missing should not exist (%v)", err)
+	}
+	if n, err := d.Count("..addr"); n != 7 || err != nil {
+		t.Errorf("Count: want 7, got %d (%v)", n, err)
+	}
+	if v, err := d.First("..addr"); v != "10.10.0.1:10001" || err != nil {
+		t.Errorf("First: unexpected result %v (%v)", v, err)
+	}
+	if v, err := d.First(".missing", "none"); v != "none" || err != nil {
+		t.Errorf("First: default not used %v (%v)", v, err)
+	}
+	if len(d.queries) == 0 {
+		t.Errorf("queries should be cached")
+	}
+}
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEach(t *testing.T) {
+	queries := []string{
+		"..addr",
+		"servers.@groups.addr",
+		"client[tls == true].addr,..groups:last",
+		"..@client:range(1,).addr",
+	}
+	for _, str := range queries {
+		q, err := Parse(str)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", str, err)
+			continue
+		}
+		want, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", str, err)
+			continue
+		}
+		var got []Result
+		err = Each(q, doc, func(r Result) bool {
+			got = append(got, r)
+			return true
+		})
+		if err != nil {
+			t.Errorf("%s: fail to iterate: %s", str, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: results mismatched! want %v, got %v", str, want, got)
+		}
+		if len(want) == 0 {
+			continue
+		}
+		got = got[:0]
+		err = Each(q, doc, func(r Result) bool {
+			got = append(got, r)
+			return false
+		})
+		if err != nil {
+			t.Errorf("%s: fail to iterate: %s", str, err)
+			continue
+		}
+		if len(got) != 1 || !reflect.DeepEqual(want[0], got[0]) {
+			t.Errorf("%s: first result mismatched! want %v, got %v", str, want[0], got)
+		}
+	}
+}
+
+func TestEachStop(t *testing.T) {
+	q, err := Parse("service,@client[unknown == 1]")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	if _, err := q.Select(doc); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected %s, got %v", ErrNotFound, err)
+	}
+	var count int
+	err = Each(q, doc, func(r Result) bool {
+		count++
+		return false
+	})
+	if err != nil || count != 1 {
+		t.Fatalf("iteration should stop before the error: %d result(s), %v", count, err)
+	}
+	err = Each(q, doc, func(r Result) bool {
+		return true
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected %s, got %v", ErrNotFound, err)
+	}
+}
+
+// selectOnly hides the Each method of the query it wraps.
+type selectOnly struct {
+	Queryer
+}
+
+func TestEachSelectOnly(t *testing.T) {
+	q, err := Parse("..addr")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	want, err := q.Select(doc)
+	if err != nil {
+		t.Fatalf("fail to select: %s", err)
+	}
+	var got []Result
+	err = Each(selectOnly{q}, doc, func(r Result) bool {
+		got = append(got, r)
+		return len(got) < 2
+	})
+	if err != nil {
+		t.Fatalf("fail to iterate: %s", err)
+	}
+	if !reflect.DeepEqual(want[:2], got) {
+		t.Errorf("results mismatched! want %v, got %v", want[:2], got)
+	}
+	got, err = Queryset{selectOnly{q}}.Select(doc)
+	if err != nil {
+		t.Fatalf("fail to select: %s", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("results mismatched! want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,202 @@
+package query
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type StepKind int
+
+const (
+	StepQuery StepKind = iota
+	StepTable
+	StepAccept
+	StepReject
+	StepSelect
+	StepMatch
+	StepResult
+	StepError
+)
+
+func (k StepKind) String() string {
+	switch k {
+	case StepQuery:
+		return "query"
+	case StepTable:
+		return "table"
+	case StepAccept:
+		return "accept"
+	case StepReject:
+		return "reject"
+	case StepSelect:
+		return "select"
+	case StepMatch:
+		return "match"
+	case StepResult:
+		return "result"
+	case StepError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Step is one step of the evaluation of a query recorded by Explain. Node is
+// the text of the query, key, selector or predicate evaluated at Path. Ok is
+// the outcome of selectors and predicates. Steps holds the steps done while
+// evaluating a query or walking a table.
+type Step struct {
+	Kind   StepKind
+	Path   Path
+	Node   string
+	Ok     bool
+	Value  interface{}
+	Detail string
+	Err    error
+	Steps  []Step
+}
+
+func (s Step) String() string {
+	var b strings.Builder
+	b.WriteString(s.Kind.String())
+	if s.Node != "" {
+		b.WriteString(" " + s.Node)
+	}
+	b.WriteString(" at ")
+	if len(s.Path) == 0 {
+		b.WriteString("<root>")
+	} else {
+		b.WriteString(s.Path.String())
+	}
+	switch s.Kind {
+	case StepSelect, StepMatch:
+		if s.Err == nil && s.Detail == "" {
+			fmt.Fprintf(&b, ": %t", s.Ok)
+		}
+	case StepResult:
+		fmt.Fprintf(&b, " = %v", s.Value)
+	}
+	if s.Detail != "" {
+		b.WriteString(": " + s.Detail)
+	}
+	if s.Err != nil {
+		b.WriteString(": " + s.Err.Error())
+	}
+	return b.String()
+}
+
+func (s Step) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Kind   string      `json:"kind"`
+		Path   string      `json:"path"`
+		Node   string      `json:"node,omitempty"`
+		Ok     *bool       `json:"ok,omitempty"`
+		Value  interface{} `json:"value,omitempty"`
+		Detail string      `json:"detail,omitempty"`
+		Err    string      `json:"error,omitempty"`
+		Steps  []Step      `json:"steps,omitempty"`
+	}{
+		Kind:   s.Kind.String(),
+		Path:   s.Path.String(),
+		Node:   s.Node,
+		Value:  s.Value,
+		Detail: s.Detail,
+		Steps:  s.Steps,
+	}
+	if s.Kind == StepSelect || s.Kind == StepMatch {
+		v.Ok = &s.Ok
+	}
+	if s.Err != nil {
+		v.Err = s.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// Trace is the record of the evaluation of a query against a document.
+type Trace struct {
+	Steps   []Step
+	Results []Result
+	Err     error
+}
+
+// Explain evaluates q against doc like Select does and records each step of
+// the evaluation: the tables walked, the keys accepted or rejected, the output
+// of the selectors, the result of the predicates and the errors. The error of
+// the evaluation, if any, is returned and kept in the Trace.
+func Explain(q Queryer, doc interface{}) (*Trace, error) {
+	var tr tracer
+	rs, err := collect(q, doc, trail{trace: &tr})
+	if err != nil {
+		tr.add(Step{Kind: StepError, Err: err})
+	}
+	t := Trace{
+		Steps:   tr.root.Steps,
+		Results: rs,
+		Err:     err,
+	}
+	return &t, err
+}
+
+// WriteTree writes the steps of the Trace as an indented tree, one step per
+// line.
+func (t *Trace) WriteTree(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	writeSteps(out, t.Steps, 0)
+	return out.Flush()
+}
+
+func (t *Trace) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(t.Steps)
+}
+
+func writeSteps(out *bufio.Writer, steps []Step, level int) {
+	for _, s := range steps {
+		out.WriteString(strings.Repeat("  ", level))
+		out.WriteString(s.String())
+		out.WriteString("\n")
+		writeSteps(out, s.Steps, level+1)
+	}
+}
+
+func (t *trail) noteAccept(key Accepter, found bool, err error) {
+	s := Step{Kind: StepAccept, Node: key.String()}
+	if err != nil || !found {
+		s.Kind, s.Err = StepReject, err
+	}
+	if !found {
+		s.Detail = "not found"
+	}
+	t.note(s)
+}
+
+type tracer struct {
+	root  Step
+	stack []*Step
+}
+
+func (t *tracer) top() *Step {
+	if n := len(t.stack); n > 0 {
+		return t.stack[n-1]
+	}
+	return &t.root
+}
+
+func (t *tracer) add(s Step) {
+	top := t.top()
+	top.Steps = append(top.Steps, s)
+}
+
+func (t *tracer) begin(s Step) {
+	top := t.top()
+	top.Steps = append(top.Steps, s)
+	t.stack = append(t.stack, &top.Steps[len(top.Steps)-1])
+}
+
+func (t *tracer) end() {
+	t.stack = t.stack[:len(t.stack)-1]
+}
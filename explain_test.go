@@ -0,0 +1,99 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	doc := map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{"mode": int64(1), "addr": "10.0.0.1"},
+			"raw",
+			map[string]interface{}{"mode": int64(2), "addr": "10.0.0.2"},
+		},
+		"owner": "midbel",
+	}
+	q, err := Parse("@groups[mode == 2].addr,..(user,owner):first")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	tr, err := Explain(q, doc)
+	if err != nil {
+		t.Fatalf("fail to explain: %s", err)
+	}
+	want, _ := q.Select(doc)
+	if !reflect.DeepEqual(want, tr.Results) {
+		t.Fatalf("results mismatched! want %v, got %v", want, tr.Results)
+	}
+	var buf bytes.Buffer
+	if err := tr.WriteTree(&buf); err != nil {
+		t.Fatalf("fail to write tree: %s", err)
+	}
+	tree := strings.Join([]string{
+		"query ..@groups[mode == 2] at <root>",
+		"  table at <root>",
+		"    accept groups at <root>",
+		"    match mode == 2 at groups[0]: false",
+		"    match mode == 2 at groups[1]: not a table",
+		"    match mode == 2 at groups[2]: true",
+		"    query .addr at groups[2]",
+		"      table at groups[2]",
+		"        accept addr at groups[2]",
+		"        result at groups[2].addr = 10.0.0.2",
+		"query ..(user,owner):first at <root>",
+		"  table at <root>",
+		"    reject user at <root>: not found",
+		"    table at groups[0]",
+		"      reject user at groups[0]: not found",
+		"    table at groups[2]",
+		"      reject user at groups[2]: not found",
+		"    accept owner at <root>",
+		"    select :first at owner: false",
+		"",
+	}, "\n")
+	if got := buf.String(); got != tree {
+		t.Errorf("tree mismatched!\nwant:\n%s\ngot:\n%s", tree, got)
+	}
+	buf.Reset()
+	if err := tr.WriteJSON(&buf); err != nil {
+		t.Fatalf("fail to write json: %s", err)
+	}
+	var steps []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &steps); err != nil {
+		t.Fatalf("invalid json: %s", err)
+	}
+	if len(steps) != 2 || steps[0]["kind"] != "query" {
+		t.Errorf("unexpected json steps: %s", buf.String())
+	}
+}
+
+func TestExplainError(t *testing.T) {
+	doc := map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{"addr": "10.0.0.1"},
+		},
+	}
+	q, err := Parse("@groups[mode == 2]")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	tr, err := Explain(q, doc)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected %s, got %v", ErrNotFound, err)
+	}
+	var (
+		match = tr.Steps[0].Steps[0].Steps[1]
+		last  = tr.Steps[len(tr.Steps)-1]
+	)
+	if match.Kind != StepMatch || !errors.Is(match.Err, ErrNotFound) {
+		t.Errorf("predicate step does not hold the error: %s", match)
+	}
+	if last.Kind != StepError || last.Err != err {
+		t.Errorf("last step should be the error: %s", last)
+	}
+}
@@ -9,249 +9,278 @@ import (
 
 type Func func(interface{}) (interface{}, error)
 
-var funcnames = map[string]func(vs []interface{}) Func{
-	"lshift":  leftShift,
-	"rshift":  rightShift,
-	"and":     and,
-	"or":      or,
-	"pow":     pow,
-	"abs":     abs,
-	"ltrim":   trimLeft,
-	"rtrim":   trimRight,
-	"lower":   toLower,
-	"upper":   toUpper,
-	"yearday": yearDay,
-	"year":    year,
-	"length":  length,
+type Call struct {
+	Name string
+	Args []interface{}
+
+	fn *builtin
 }
 
-func leftShift(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(1, args); err != nil {
-			return nil, fmt.Errorf("lshift: %w", err)
-		}
-		value, err := toInt(ifi)
-		if err != nil {
-			return nil, err
-		}
-		count, err := toInt(args[0])
-		if err == nil {
-			value <<= count
-		}
-		return value, err
+// makeCall gives a call to the function name with its implementation already
+// looked up.
+func makeCall(name string, args []interface{}) (Call, error) {
+	fn, ok := funcnames[name]
+	if !ok {
+		return Call{Name: name, Args: args}, fmt.Errorf("unknown function %q", name)
 	}
+	return Call{Name: name, Args: args, fn: fn}, nil
 }
 
-func rightShift(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(1, args); err != nil {
-			return nil, fmt.Errorf("rshift: %w", err)
+func (c Call) Eval(ifi interface{}) (interface{}, error) {
+	fn := c.fn
+	if fn == nil {
+		var ok bool
+		if fn, ok = funcnames[c.Name]; !ok {
+			return nil, fmt.Errorf("%s: unknown function", c.Name)
 		}
-		value, err := toInt(ifi)
-		if err != nil {
-			return nil, err
-		}
-		count, err := toInt(args[0])
-		if err == nil {
-			value >>= count
-		}
-		return value, err
 	}
+	return fn.call(c.Args, ifi)
 }
 
-func and(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(1, args); err != nil {
-			return nil, fmt.Errorf("and: %w", err)
-		}
-		value, err := toInt(ifi)
-		if err != nil {
-			return nil, err
-		}
-		count, err := toInt(args[0])
-		if err == nil {
-			value &= count
-		}
-		return value, err
+func (c Call) String() string {
+	if len(c.Args) == 0 {
+		return c.Name
 	}
+	return c.Name + formatValue(c.Args, 0)
 }
 
-func or(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(1, args); err != nil {
-			return nil, fmt.Errorf("or: %w", err)
-		}
-		value, err := toInt(ifi)
-		if err != nil {
-			return nil, err
-		}
-		count, err := toInt(args[0])
-		if err == nil {
-			value |= count
-		}
-		return value, err
+// builtin is a function callable in predicates. It is given the arguments of
+// the call with the value of the option.
+type builtin struct {
+	call func(args []interface{}, ifi interface{}) (interface{}, error)
+}
+
+var funcnames = map[string]*builtin{
+	"lshift":  {leftShift},
+	"rshift":  {rightShift},
+	"and":     {and},
+	"or":      {or},
+	"pow":     {pow},
+	"abs":     {abs},
+	"ltrim":   {trimLeft},
+	"rtrim":   {trimRight},
+	"lower":   {toLower},
+	"upper":   {toUpper},
+	"yearday": {yearDay},
+	"year":    {year},
+	"length":  {length},
+}
+
+func leftShift(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(1, args); err != nil {
+		return nil, fmt.Errorf("lshift: %w", err)
+	}
+	value, err := toInt(ifi)
+	if err != nil {
+		return nil, err
+	}
+	count, err := toInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("negative shift count %d", count)
 	}
+	return value << count, nil
 }
 
-func pow(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(1, args); err != nil {
-			return nil, fmt.Errorf("pow: %w", err)
-		}
-		exp, err := toFloat(args[0])
-		if err != nil {
-			return nil, err
-		}
-		switch value := ifi.(type) {
-		case float64:
-			return math.Pow(value, exp), nil
-		case int64:
-			val := math.Pow(float64(value), exp)
-			return int64(val), nil
-		default:
-			return nil, castError("number", ifi)
-		}
+func rightShift(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(1, args); err != nil {
+		return nil, fmt.Errorf("rshift: %w", err)
+	}
+	value, err := toInt(ifi)
+	if err != nil {
+		return nil, err
+	}
+	count, err := toInt(args[0])
+	if err != nil {
+		return nil, err
 	}
+	if count < 0 {
+		return nil, fmt.Errorf("negative shift count %d", count)
+	}
+	return value >> count, nil
 }
 
-func abs(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(0, args); err != nil {
-			return nil, fmt.Errorf("abs: %w", err)
-		}
-		switch value := ifi.(type) {
-		case float64:
-			return math.Abs(value), nil
-		case int64:
-			val := math.Abs(float64(value))
-			return int64(val), nil
-		default:
-			return nil, castError("number", ifi)
-		}
+func and(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(1, args); err != nil {
+		return nil, fmt.Errorf("and: %w", err)
+	}
+	value, err := toInt(ifi)
+	if err != nil {
+		return nil, err
+	}
+	count, err := toInt(args[0])
+	if err == nil {
+		value &= count
 	}
+	return value, err
 }
 
-func yearDay(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(0, args); err != nil {
-			return nil, fmt.Errorf("yearday: %w", err)
-		}
-		value, err := toTime(ifi)
-		if err != nil {
-			return nil, err
-		}
-		return value.YearDay(), nil
+func or(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(1, args); err != nil {
+		return nil, fmt.Errorf("or: %w", err)
+	}
+	value, err := toInt(ifi)
+	if err != nil {
+		return nil, err
 	}
+	count, err := toInt(args[0])
+	if err == nil {
+		value |= count
+	}
+	return value, err
 }
 
-func year(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(0, args); err != nil {
-			return nil, fmt.Errorf("year: %w", err)
-		}
-		value, err := toTime(ifi)
-		if err != nil {
-			return nil, err
-		}
-		return value.Year(), nil
+func pow(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(1, args); err != nil {
+		return nil, fmt.Errorf("pow: %w", err)
+	}
+	exp, err := toFloat(args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch value := ifi.(type) {
+	case float64:
+		return math.Pow(value, exp), nil
+	case int64:
+		val := math.Pow(float64(value), exp)
+		if i, err := toInt(val); err == nil {
+			return i, nil
+		}
+		return val, nil
+	default:
+		return nil, castError("number", ifi)
 	}
 }
 
-func toLower(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(0, args); err != nil {
-			return nil, fmt.Errorf("lower: %w", err)
-		}
-		str, err := toString(ifi)
-		if err == nil {
-			str = strings.ToLower(str)
-		}
-		return str, err
+func abs(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(0, args); err != nil {
+		return nil, fmt.Errorf("abs: %w", err)
+	}
+	switch value := ifi.(type) {
+	case float64:
+		return math.Abs(value), nil
+	case int64:
+		if value == math.MinInt64 {
+			return nil, fmt.Errorf("abs: %d overflows integer", value)
+		}
+		if value < 0 {
+			value = -value
+		}
+		return value, nil
+	default:
+		return nil, castError("number", ifi)
 	}
 }
 
-func toUpper(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(0, args); err != nil {
-			return nil, fmt.Errorf("upper: %w", err)
-		}
-		str, err := toString(ifi)
-		if err == nil {
-			str = strings.ToUpper(str)
-		}
-		return str, err
+func yearDay(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(0, args); err != nil {
+		return nil, fmt.Errorf("yearday: %w", err)
+	}
+	value, err := toTime(ifi)
+	if err != nil {
+		return nil, err
 	}
+	return int64(value.YearDay()), nil
 }
 
-func trimLeft(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(2, args); err != nil {
-			return nil, fmt.Errorf("ltrim: %w", err)
-		}
-		str, err := toString(ifi)
-		if err != nil {
-			return nil, err
-		}
-		left, err := toString(args[0])
-		if err != nil {
-			return nil, err
-		}
-		long, err := toBool(args[1])
-		if err != nil {
-			return nil, err
-		}
-		for i := 0; strings.HasPrefix(str, left); i++ {
-			if long && i > 0 {
-				break
-			}
-			str = strings.TrimPrefix(str, left)
-		}
-		return str, nil
+func year(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(0, args); err != nil {
+		return nil, fmt.Errorf("year: %w", err)
 	}
+	value, err := toTime(ifi)
+	if err != nil {
+		return nil, err
+	}
+	return int64(value.Year()), nil
 }
 
-func trimRight(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(2, args); err != nil {
-			return nil, fmt.Errorf("rtrim: %w", err)
-		}
-		str, err := toString(ifi)
-		if err != nil {
-			return nil, err
-		}
-		right, err := toString(args[0])
-		if err != nil {
-			return nil, err
-		}
-		long, err := toBool(args[1])
-		if err != nil {
-			return nil, err
-		}
-		for i := 0; strings.HasSuffix(str, right); i++ {
-			if long && i > 0 {
-				break
-			}
-			str = strings.TrimSuffix(str, right)
-		}
-		return str, nil
+func toLower(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(0, args); err != nil {
+		return nil, fmt.Errorf("lower: %w", err)
+	}
+	str, err := toString(ifi)
+	if err == nil {
+		str = strings.ToLower(str)
 	}
+	return str, err
 }
 
-func length(args []interface{}) Func {
-	return func(ifi interface{}) (interface{}, error) {
-		if err := checkLength(0, args); err != nil {
-			return nil, fmt.Errorf("length: %w", err)
+func toUpper(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(0, args); err != nil {
+		return nil, fmt.Errorf("upper: %w", err)
+	}
+	str, err := toString(ifi)
+	if err == nil {
+		str = strings.ToUpper(str)
+	}
+	return str, err
+}
+
+func trimLeft(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(2, args); err != nil {
+		return nil, fmt.Errorf("ltrim: %w", err)
+	}
+	str, err := toString(ifi)
+	if err != nil {
+		return nil, err
+	}
+	left, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	long, err := toBool(args[1])
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; left != "" && strings.HasPrefix(str, left); i++ {
+		if long && i > 0 {
+			break
 		}
-		switch ifi := ifi.(type) {
-		case string:
-			return len(ifi), nil
-		case []interface{}:
-			return len(ifi), nil
-		case map[string]interface{}:
-			return len(ifi), nil
-		default:
-			return nil, fmt.Errorf("length can not be applied on boolean/number")
+		str = strings.TrimPrefix(str, left)
+	}
+	return str, nil
+}
+
+func trimRight(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(2, args); err != nil {
+		return nil, fmt.Errorf("rtrim: %w", err)
+	}
+	str, err := toString(ifi)
+	if err != nil {
+		return nil, err
+	}
+	right, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	long, err := toBool(args[1])
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; right != "" && strings.HasSuffix(str, right); i++ {
+		if long && i > 0 {
+			break
 		}
+		str = strings.TrimSuffix(str, right)
+	}
+	return str, nil
+}
+
+func length(args []interface{}, ifi interface{}) (interface{}, error) {
+	if err := checkLength(0, args); err != nil {
+		return nil, fmt.Errorf("length: %w", err)
+	}
+	switch ifi := ifi.(type) {
+	case string:
+		return int64(len(ifi)), nil
+	case []interface{}:
+		return int64(len(ifi)), nil
+	case map[string]interface{}:
+		return int64(len(ifi)), nil
+	default:
+		return nil, fmt.Errorf("length can not be applied on boolean/number")
 	}
 }
 
@@ -263,19 +292,25 @@ func checkLength(want int, args []interface{}) error {
 }
 
 func toInt(ifi interface{}) (int64, error) {
-	i, ok := ifi.(int64)
-	if !ok {
+	switch i := ifi.(type) {
+	case int64:
+		return i, nil
+	case float64:
+		if i != math.Trunc(i) || i < minInt64Float || i >= maxInt64Float {
+			return 0, castError("int", ifi)
+		}
+		return int64(i), nil
+	default:
 		return 0, castError("int", ifi)
 	}
-	return i, nil
 }
 
 func toFloat(ifi interface{}) (float64, error) {
-	i, ok := ifi.(float64)
+	f, ok := asFloat(ifi)
 	if !ok {
 		return 0, castError("float", ifi)
 	}
-	return i, nil
+	return f, nil
 }
 
 func toBool(ifi interface{}) (bool, error) {
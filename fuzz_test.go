@@ -0,0 +1,83 @@
+//go:build go1.18
+// +build go1.18
+
+package query
+
+import (
+	"testing"
+)
+
+var seeds = []string{
+	".service",
+	"..addr",
+	".client[tls == true].addr",
+	"@groups:first.addr",
+	"@groups:last[mode].addr",
+	".client:range(1,)[rps].cred.user",
+	"..cred.user",
+	"..cred[user]^.addr..^[tls]",
+	".**.$*[user].%*",
+	".dependency:entries[key ~= /toml*/].value",
+	"client:at(-1)",
+	"$(client,servers).addr",
+	"/s*/[addr ~= '10.*']",
+	"..groups[every.abs >= 60 && mode != null]",
+	"%\"tls\\u00e9\\U0001F600\"",
+	".client[port.pow(2) < 0x10_00 || every == 1979-05-27T07:32:00Z]",
+	"[",
+	"..!",
+	"a:",
+}
+
+func FuzzScanner(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, str string) {
+		s := NewScanner(str)
+		for i := 0; ; i++ {
+			if i > len(str)+1 {
+				t.Fatalf("%q: scanner does not reach the end of input", str)
+			}
+			if tok := s.Scan(); tok.Type == TokEOF {
+				break
+			}
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, str string) {
+		q, err := Parse(str)
+		if err == nil && q == nil {
+			t.Fatalf("%q: no query and no error", str)
+		}
+		if err != nil {
+			return
+		}
+		canon := q.(Node).String()
+		other, err := Parse(canon)
+		if err != nil {
+			t.Fatalf("%q: fail to parse %q: %s", str, canon, err)
+		}
+		if got := other.(Node).String(); got != canon {
+			t.Fatalf("%q: %q printed as %q", str, canon, got)
+		}
+	})
+}
+
+func FuzzSelect(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, str string) {
+		q, err := Parse(str)
+		if err != nil {
+			return
+		}
+		q.Select(doc)
+	})
+}
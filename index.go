@@ -0,0 +1,199 @@
+package query
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// KeyIndex is a document with an index of the tables holding each of its keys.
+// A KeyIndex is given to Select or Each in place of the document it wraps:
+// the queries looking for a key at any level use the index instead of walking
+// the sub tables of the document. Results are the same as the ones of the
+// document without index.
+//
+// The document should not be modified once indexed. A KeyIndex can be shared
+// by concurrent queries.
+type KeyIndex struct {
+	doc  interface{}
+	root *indexNode
+	keys map[string][]*indexNode
+
+	patterns sync.Map
+}
+
+type indexNode struct {
+	path  Path
+	table map[string]interface{}
+	ptr   uintptr
+	pre   int
+	end   int
+	nodes map[Segment]*indexNode
+}
+
+func NewKeyIndex(doc interface{}) *KeyIndex {
+	x := KeyIndex{
+		doc:  doc,
+		keys: make(map[string][]*indexNode),
+	}
+	var (
+		count int
+		at    = trail{root: doc}
+	)
+	x.root = x.index(doc, nil, &count, &at)
+	return &x
+}
+
+func (x *KeyIndex) Document() interface{} {
+	return x.doc
+}
+
+// index walks the tables of ifi in the same order as traverseMap and
+// traverseArray do, skipping the same values referring back to their parents,
+// and numbers them in this order.
+func (x *KeyIndex) index(ifi interface{}, path Path, count *int, at *trail) *indexNode {
+	n := indexNode{
+		path:  path,
+		nodes: make(map[Segment]*indexNode),
+	}
+	switch is := normalize(ifi).(type) {
+	case map[string]interface{}:
+		*count++
+		n.pre, n.table = *count, is
+		if _, ok := ifi.(map[string]interface{}); ok {
+			n.ptr = reflect.ValueOf(ifi).Pointer()
+		}
+		keys := sortedKeys(is)
+		for _, k := range keys {
+			x.keys[k] = append(x.keys[k], &n)
+		}
+		for _, k := range keys {
+			seg := Key(k)
+			switch normalize(is[k]).(type) {
+			case map[string]interface{}, []interface{}:
+				if at.enter(is[k]) {
+					n.nodes[seg] = x.index(is[k], appendPath(path, seg), count, at)
+					at.leave(is[k])
+				}
+			}
+		}
+	case []interface{}:
+		for j, i := range is {
+			seg := Index(j)
+			switch normalize(i).(type) {
+			case map[string]interface{}, []interface{}:
+				if at.enter(i) {
+					n.nodes[seg] = x.index(i, appendPath(path, seg), count, at)
+					at.leave(i)
+				}
+			}
+		}
+	}
+	n.end = *count
+	return &n
+}
+
+// lookup gives the node of the table found at path or nil when the table is
+// not (or no more) the one given.
+func (x *KeyIndex) lookup(path Path, table map[string]interface{}) *indexNode {
+	n := x.root
+	for _, seg := range path {
+		if n = n.nodes[seg]; n == nil {
+			return nil
+		}
+	}
+	if n.table == nil || (n.ptr != 0 && n.ptr != reflect.ValueOf(table).Pointer()) {
+		return nil
+	}
+	return n
+}
+
+// tables gives the tables that could be accepted by key in the order they are
+// walked. It returns false when key can not be looked up in the index.
+func (x *KeyIndex) tables(key Accepter) ([]*indexNode, bool) {
+	switch key := key.(type) {
+	case Name:
+		return x.keys[key.Label], true
+	case Pattern:
+		if ns, ok := x.patterns.Load(key.Pattern); ok {
+			return ns.([]*indexNode), true
+		}
+		seen := make(map[*indexNode]struct{})
+		var ns []*indexNode
+		for k, is := range x.keys {
+			if !Match(key.Pattern, k) {
+				continue
+			}
+			for _, n := range is {
+				if _, ok := seen[n]; ok {
+					continue
+				}
+				seen[n] = struct{}{}
+				ns = append(ns, n)
+			}
+		}
+		sort.Slice(ns, func(i, j int) bool {
+			return ns[i].pre < ns[j].pre
+		})
+		x.patterns.Store(key.Pattern, ns)
+		return ns, true
+	default:
+		return nil, false
+	}
+}
+
+// selectFromIndex gives the same results as traverseMap with the tables below
+// ifi holding key found in the index. It returns false when the index can not
+// be used for ifi or key.
+func (q Query) selectFromIndex(key Accepter, at *trail, ifi map[string]interface{}) (bool, error) {
+	curr := at.index.lookup(at.segs, ifi)
+	if curr == nil {
+		return false, nil
+	}
+	ns, ok := at.index.tables(key)
+	if !ok {
+		return false, nil
+	}
+	x := sort.Search(len(ns), func(i int) bool {
+		return ns[i].pre > curr.pre
+	})
+	bound := curr.pre
+	for _, n := range ns[x:] {
+		if n.pre > curr.end {
+			break
+		}
+		if n.pre <= bound {
+			continue
+		}
+		bound = n.end
+		if err := q.selectFromIndexNode(key, at, n); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (q Query) selectFromIndexNode(key Accepter, at *trail, n *indexNode) error {
+	var (
+		depth = len(at.segs)
+		err   error
+	)
+	for _, seg := range n.path[depth:] {
+		if err = at.push(seg); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		at.begin(Step{Kind: StepTable})
+		err = q.selectFromMapWithKey(key, at, n.table)
+		at.end()
+	}
+	at.segs = at.segs[:depth]
+	return err
+}
+
+func appendPath(p Path, seg Segment) Path {
+	ps := make(Path, 0, len(p)+1)
+	ps = append(ps, p...)
+	return append(ps, seg)
+}
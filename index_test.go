@@ -0,0 +1,117 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeyIndex(t *testing.T) {
+	cfg := testConfig{
+		testMeta: testMeta{Owner: "midbel", Created: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)},
+		Clients: []testClient{
+			{Addr: "10.0.0.1", Cred: &testCred{User: "root"}},
+			{Addr: "10.0.0.2", TLS: true, Tags: []string{"prod"}},
+		},
+		Servers: map[string]testClient{
+			"prime": {Addr: "10.1.0.1", Cred: &testCred{User: "admin"}},
+		},
+	}
+	nested := map[string]interface{}{
+		"matrix": []interface{}{
+			[]interface{}{
+				map[string]interface{}{"leaf": "a", "sub": map[string]interface{}{"leaf": "b"}},
+			},
+			map[string]interface{}{"other": map[string]interface{}{"leaf": "c"}},
+		},
+		"leaf": []interface{}{1, 2},
+	}
+	data := []struct {
+		Doc     interface{}
+		Queries []string
+	}{
+		{
+			Doc: doc,
+			Queries: []string{
+				"..addr",
+				"..user",
+				"..@groups:last",
+				"servers..addr",
+				"..$cred[user == \"user2\"].passwd",
+				"../[a-c]*/",
+				"..(missing,/pass*/)",
+				"client..user",
+				"..@client[tls == true].cred..user",
+				"..missing",
+				"..%addr",
+				"..$addr",
+			},
+		},
+		{
+			Doc:     tree,
+			Queries: []string{"..leaf", "..node-1..leaf", "..node:last..leaf", "../node-?/"},
+		},
+		{
+			Doc:     &cfg,
+			Queries: []string{"..user", "..addr", "servers..cred", "..tags:first"},
+		},
+		{
+			Doc:     nested,
+			Queries: []string{"..leaf", "matrix..leaf", "..sub..leaf", "..other"},
+		},
+	}
+	for _, d := range data {
+		x := NewKeyIndex(d.Doc)
+		for _, str := range d.Queries {
+			q, err := Parse(str)
+			if err != nil {
+				t.Errorf("%s: fail to parse: %s", str, err)
+				continue
+			}
+			want, err1 := q.Select(d.Doc)
+			got, err2 := q.Select(x)
+			if (err1 == nil) != (err2 == nil) {
+				t.Errorf("%s: errors mismatched! want %v, got %v", str, err1, err2)
+				continue
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("%s: results mismatched!\nwant %v\ngot  %v", str, want, got)
+			}
+		}
+	}
+}
+
+func TestKeyIndexNodes(t *testing.T) {
+	q, err := Parse("..missing")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	opts := Options{MaxNodes: 50}
+	_, err = SelectContext(context.Background(), q, tree, opts)
+	if !errors.As(err, new(LimitError)) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	rs, err := SelectContext(context.Background(), q, NewKeyIndex(tree), opts)
+	if err != nil {
+		t.Fatalf("fail to select with index: %s", err)
+	}
+	if len(rs) != 0 {
+		t.Fatalf("results mismatched! want 0, got %d", len(rs))
+	}
+}
+
+var tree = makeTree(6, 3)
+
+func BenchmarkSelectMissing(b *testing.B) {
+	benchmarkSelect(b, "..missing", makeTree(10, 2))
+}
+
+func BenchmarkSelectIndexMissing(b *testing.B) {
+	benchmarkSelect(b, "..missing", NewKeyIndex(makeTree(10, 2)))
+}
+
+func BenchmarkSelectIndexDeep(b *testing.B) {
+	benchmarkSelect(b, "..leaf", NewKeyIndex(makeTree(10, 2)))
+}
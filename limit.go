@@ -0,0 +1,91 @@
+package query
+
+import (
+	"context"
+	"fmt"
+)
+
+type Limit int
+
+const (
+	LimitResults Limit = iota
+	LimitDepth
+	LimitNodes
+)
+
+func (k Limit) String() string {
+	switch k {
+	case LimitResults:
+		return "results"
+	case LimitDepth:
+		return "depth"
+	case LimitNodes:
+		return "nodes"
+	default:
+		return "unknown"
+	}
+}
+
+type LimitError struct {
+	Limit Limit
+	Max   int
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("query: limit of %d %s exceeded", e.Max, e.Limit)
+}
+
+// Options bounds the evaluation of a query. A zero value means that the
+// corresponding limit is not enforced.
+type Options struct {
+	MaxResults int
+	MaxDepth   int
+	MaxNodes   int
+}
+
+func SelectContext(ctx context.Context, q Queryer, doc interface{}, opts Options) ([]Result, error) {
+	at := trail{
+		limits: &limits{
+			Options: opts,
+			ctx:     ctx,
+		},
+	}
+	return collect(q, doc, at)
+}
+
+func (q Query) SelectContext(ctx context.Context, doc interface{}, opts Options) ([]Result, error) {
+	return SelectContext(ctx, q, doc, opts)
+}
+
+func (qs Queryset) SelectContext(ctx context.Context, doc interface{}, opts Options) ([]Result, error) {
+	return SelectContext(ctx, qs, doc, opts)
+}
+
+type limits struct {
+	Options
+	ctx     context.Context
+	nodes   int
+	results int
+}
+
+func (i *limits) visit(depth int) error {
+	if err := i.ctx.Err(); err != nil {
+		return err
+	}
+	i.nodes++
+	if i.MaxNodes > 0 && i.nodes > i.MaxNodes {
+		return LimitError{Limit: LimitNodes, Max: i.MaxNodes}
+	}
+	if i.MaxDepth > 0 && depth > i.MaxDepth {
+		return LimitError{Limit: LimitDepth, Max: i.MaxDepth}
+	}
+	return nil
+}
+
+func (i *limits) emit(n int) error {
+	i.results += n
+	if i.MaxResults > 0 && i.results > i.MaxResults {
+		return LimitError{Limit: LimitResults, Max: i.MaxResults}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSelectContext(t *testing.T) {
+	tree := makeTree(4, 3)
+	data := []struct {
+		Input string
+		Options
+		Limit Limit
+		Fail  bool
+	}{
+		{
+			Input: "..leaf",
+		},
+		{
+			Input:   "..leaf",
+			Options: Options{MaxResults: 1000, MaxDepth: 100, MaxNodes: 10000},
+		},
+		{
+			Input:   "..leaf",
+			Options: Options{MaxResults: 10},
+			Limit:   LimitResults,
+			Fail:    true,
+		},
+		{
+			Input:   "..leaf",
+			Options: Options{MaxDepth: 4},
+			Limit:   LimitDepth,
+			Fail:    true,
+		},
+		{
+			Input:   "..leaf",
+			Options: Options{MaxNodes: 50},
+			Limit:   LimitNodes,
+			Fail:    true,
+		},
+		{
+			Input:   ".root.leaf",
+			Options: Options{MaxResults: 1, MaxDepth: 2, MaxNodes: 2},
+		},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		rs, err := SelectContext(context.Background(), q, tree, d.Options)
+		if !d.Fail {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", d.Input, err)
+				continue
+			}
+			want, _ := q.Select(tree)
+			if len(rs) != len(want) {
+				t.Errorf("%s: results mismatched! want %d, got %d", d.Input, len(want), len(rs))
+			}
+			continue
+		}
+		var e LimitError
+		if !errors.As(err, &e) {
+			t.Errorf("%s: expected limit error, got %v", d.Input, err)
+			continue
+		}
+		if e.Limit != d.Limit {
+			t.Errorf("%s: limit mismatched! want %s, got %s", d.Input, d.Limit, e.Limit)
+		}
+	}
+}
+
+func TestSelectContextCancel(t *testing.T) {
+	q, err := Parse("..leaf")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = SelectContext(ctx, q, makeTree(4, 3), Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+package query
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissing   = errors.New("value not found")
+	ErrAmbiguous = errors.New("too many values found")
+)
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	unmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type FieldError struct {
+	Field string
+	Query string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	if e.Query == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Field, e.Query, e.Err)
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+type LoadError []FieldError
+
+func (e LoadError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "load: %d field(s) can not be loaded", len(e))
+	for _, fe := range e {
+		b.WriteString("; ")
+		b.WriteString(fe.Error())
+	}
+	return b.String()
+}
+
+// Load fills the fields of the struct pointed to by v with the results of the
+// queries given in their query tag, eg:
+//
+//	Version string `query:"..@dependency:first.version,required" default:"1.0"`
+//
+// A field marked as required fails when its query gives no result. Other
+// fields keep their zero value or get the value of their default tag. Untagged
+// struct fields are loaded from the same document. Every field that can not be
+// loaded is reported in the returned LoadError.
+func Load(doc interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("load: pointer to struct expected (got %T)", v)
+	}
+	var errs LoadError
+	loadStruct(doc, rv.Elem(), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type fieldTag struct {
+	query    string
+	required bool
+	fallback string
+	hasDef   bool
+}
+
+func parseFieldTag(f reflect.StructField) (fieldTag, bool) {
+	var ft fieldTag
+	str, ok := f.Tag.Lookup("query")
+	if !ok {
+		return ft, false
+	}
+	for {
+		x := strings.LastIndexByte(str, ',')
+		if x < 0 {
+			break
+		}
+		opt := strings.TrimSpace(str[x+1:])
+		if opt != "required" && opt != "optional" {
+			break
+		}
+		ft.required = opt == "required"
+		str = str[:x]
+	}
+	ft.query = strings.TrimSpace(str)
+	ft.fallback, ft.hasDef = f.Tag.Lookup("default")
+	return ft, true
+}
+
+func loadStruct(doc interface{}, v reflect.Value, prefix string, errs *LoadError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft, ok := parseFieldTag(f)
+		// the fields of an unexported embedded struct can be set but not the
+		// struct itself: it is only walked when it has no query of its own.
+		if f.PkgPath != "" && (ok || !isEmbeddedStruct(f)) {
+			continue
+		}
+		name := prefix + f.Name
+		if !ok {
+			if f.Type.Kind() == reflect.Struct && f.Type != timeType {
+				loadStruct(doc, v.Field(i), name+".", errs)
+			}
+			continue
+		}
+		if ft.query == "-" {
+			continue
+		}
+		if err := loadField(doc, v.Field(i), ft, name, errs); err != nil {
+			*errs = append(*errs, FieldError{
+				Field: name,
+				Query: ft.query,
+				Err:   err,
+			})
+		}
+	}
+}
+
+func loadField(doc interface{}, v reflect.Value, ft fieldTag, name string, errs *LoadError) error {
+	if !v.CanSet() {
+		return fmt.Errorf("%s can not be set", v.Type())
+	}
+	q, err := Parse(ft.query)
+	if err != nil {
+		return err
+	}
+	rs, err := q.Select(doc)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(rs) == 0 && ft.hasDef:
+		return loadDefault(v, ft.fallback)
+	case len(rs) == 0 && ft.required:
+		return ErrMissing
+	case len(rs) == 0:
+		return nil
+	case len(rs) == 1:
+		return loadValue(rs[0].Value, v, name, errs)
+	}
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("%w (%d results)", ErrAmbiguous, len(rs))
+	}
+	vs := make([]interface{}, len(rs))
+	for i := range rs {
+		vs[i] = rs[i].Value
+	}
+	return loadValue(vs, v, name, errs)
+}
+
+func loadValue(ifi interface{}, v reflect.Value, name string, errs *LoadError) error {
+	if ifi != nil {
+		if rv := reflect.ValueOf(ifi); rv.Type().AssignableTo(v.Type()) {
+			v.Set(rv)
+			return nil
+		}
+	}
+	value := normalize(ifi)
+	if isNull(value) {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Type() {
+	case timeType:
+		return loadTime(value, v)
+	case durationType:
+		return loadDuration(value, v)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		str, ok := value.(string)
+		if !ok {
+			return castError(v.Type().String(), ifi)
+		}
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := loadValue(ifi, elem.Elem(), name, errs); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(ifi))
+	case reflect.Struct:
+		doc, ok := value.(map[string]interface{})
+		if !ok {
+			return castError("table", ifi)
+		}
+		loadStruct(doc, v, name+".", errs)
+	case reflect.Slice:
+		arr, ok := value.([]interface{})
+		if !ok {
+			arr = []interface{}{ifi}
+		}
+		slice := reflect.MakeSlice(v.Type(), len(arr), len(arr))
+		for i := range arr {
+			elem := fmt.Sprintf("%s[%d]", name, i)
+			if err := loadValue(arr[i], slice.Index(i), elem, errs); err != nil {
+				return fmt.Errorf("%s: %w", elem, err)
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		doc, ok := value.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return castError(v.Type().String(), ifi)
+		}
+		tab := reflect.MakeMapWithSize(v.Type(), len(doc))
+		for k, i := range doc {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := loadValue(i, elem, name+"."+k, errs); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			tab.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), elem)
+		}
+		v.Set(tab)
+	default:
+		return loadScalar(value, v)
+	}
+	return nil
+}
+
+func loadScalar(value interface{}, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		str, ok := value.(string)
+		if !ok {
+			return castError("string", value)
+		}
+		v.SetString(str)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return castError("bool", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt(value)
+		if err != nil || v.OverflowInt(i) {
+			return castError(v.Type().String(), value)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := toInt(value)
+		if err != nil || i < 0 || v.OverflowUint(uint64(i)) {
+			return castError(v.Type().String(), value)
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(value)
+		if err != nil || (v.Kind() == reflect.Float32 && math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0)) {
+			return castError(v.Type().String(), value)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("%s: unsupported type", v.Type())
+	}
+	return nil
+}
+
+func loadTime(value interface{}, v reflect.Value) error {
+	switch value := value.(type) {
+	case time.Time:
+		v.Set(reflect.ValueOf(value))
+	case string:
+		w, err := parseTime(value)
+		if err != nil {
+			return castError("time", value)
+		}
+		v.Set(reflect.ValueOf(w))
+	default:
+		return castError("time", value)
+	}
+	return nil
+}
+
+func loadDuration(value interface{}, v reflect.Value) error {
+	switch value := value.(type) {
+	case int64:
+		v.SetInt(value)
+	case string:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return castError("duration", value)
+		}
+		v.SetInt(int64(d))
+	default:
+		return castError("duration", value)
+	}
+	return nil
+}
+
+// loadDefault converts the value of a default tag to the type of the field.
+// Values of slices are separated by commas.
+func loadDefault(v reflect.Value, str string) error {
+	value, err := parseDefault(v.Type(), str)
+	if err != nil {
+		return fmt.Errorf("default %q: %w", str, err)
+	}
+	return loadValue(value, v, "", nil)
+}
+
+func parseDefault(t reflect.Type, str string) (interface{}, error) {
+	if t == timeType || t == durationType || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return str, nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return parseDefault(t.Elem(), str)
+	case reflect.Slice:
+		var arr []interface{}
+		for _, s := range strings.Split(str, ",") {
+			v, err := parseDefault(t.Elem(), strings.TrimSpace(s))
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case reflect.Bool:
+		return strconv.ParseBool(str)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseInt(str, 0, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(str, 64)
+	case reflect.String, reflect.Interface:
+		return str, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported type", t)
+	}
+}
+
+func parseTime(str string) (time.Time, error) {
+	var (
+		w   time.Time
+		err error
+	)
+	for _, fs := range [][]string{datestr, {"2006-01-02"}, timestr} {
+		for _, f := range fs {
+			if w, err = time.Parse(f, str); err == nil {
+				return w, nil
+			}
+		}
+	}
+	return w, err
+}
@@ -0,0 +1,174 @@
+package query
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDependency struct {
+	Name     string `query:".name,required"`
+	Version  string `query:".version" default:"latest"`
+	Optional bool   `query:".optional"`
+}
+
+type testSettings struct {
+	Service string            `query:".service,required"`
+	Admin   string            `query:".admin.name"`
+	Born    time.Time         `query:".admin.dob"`
+	Every   time.Duration     `query:"..every" default:"5s"`
+	Ports   []uint16          `query:"..port"`
+	Modes   []int             `query:"@groups:first.mode,optional"`
+	Users   []string          `query:"..cred.user"`
+	First   *string           `query:"client:first.addr"`
+	Prime   testServer        `query:".servers.prime"`
+	Groups  []testServer      `query:"@groups"`
+	Names   map[string]string `query:".admin"`
+	IP      net.IP            `query:".ip" default:"127.0.0.1"`
+	Retries int               `query:".retries" default:"3"`
+	Ratios  []float64         `query:".ratios" default:"0.5, 1"`
+	Raw     interface{}       `query:".admin.email"`
+	Ignore  string            `query:"-"`
+	Nested  struct {
+		Backup string `query:".servers.backup.addr"`
+	}
+}
+
+type testServer struct {
+	Addr   string `query:".addr,required"`
+	Reboot bool   `query:".reboot"`
+}
+
+func TestLoad(t *testing.T) {
+	doc := map[string]interface{}{
+		"service": "query",
+		"admin": map[string]interface{}{
+			"name":  "midbel",
+			"email": "midbel@foobar.org",
+		},
+		"client": []interface{}{
+			map[string]interface{}{"addr": "10.0.0.1", "port": int64(80), "cred": map[string]interface{}{"user": "root"}},
+			map[string]interface{}{"addr": "10.0.0.2", "port": int64(443), "cred": map[string]interface{}{"user": "admin"}},
+		},
+		"servers": map[string]interface{}{
+			"prime":  map[string]interface{}{"addr": "10.1.0.1", "reboot": true},
+			"backup": map[string]interface{}{"addr": "10.1.0.2"},
+			"groups": []interface{}{
+				map[string]interface{}{"addr": "239.0.0.1", "mode": int64(1)},
+				map[string]interface{}{"addr": "239.0.0.2", "mode": int64(2)},
+			},
+		},
+	}
+	var s testSettings
+	if err := Load(doc, &s); err != nil {
+		t.Fatalf("fail to load: %s", err)
+	}
+	first := "10.0.0.1"
+	want := testSettings{
+		Service: "query",
+		Admin:   "midbel",
+		Every:   5 * time.Second,
+		Ports:   []uint16{80, 443},
+		Modes:   []int{1},
+		Users:   []string{"root", "admin"},
+		First:   &first,
+		Prime:   testServer{Addr: "10.1.0.1", Reboot: true},
+		Groups: []testServer{
+			{Addr: "239.0.0.1"},
+			{Addr: "239.0.0.2"},
+		},
+		Names: map[string]string{
+			"name":  "midbel",
+			"email": "midbel@foobar.org",
+		},
+		IP:      net.ParseIP("127.0.0.1"),
+		Retries: 3,
+		Ratios:  []float64{0.5, 1},
+		Raw:     "midbel@foobar.org",
+	}
+	want.Nested.Backup = "10.1.0.2"
+	if !reflect.DeepEqual(want, s) {
+		t.Errorf("settings mismatched!\nwant %+v\ngot  %+v", want, s)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	doc := map[string]interface{}{
+		"name":  int64(42),
+		"size":  int64(300),
+		"addr":  []interface{}{"10.0.0.1", "10.0.0.2"},
+		"table": map[string]interface{}{"tls": "yes"},
+	}
+	var s struct {
+		Name    string `query:".name"`
+		Size    uint8  `query:".size"`
+		Missing string `query:".missing,required"`
+		Addr    string `query:"addr:string"`
+		Port    int    `query:".port" default:"http"`
+		Bad     string `query:"[name"`
+		Table   struct {
+			TLS bool `query:".tls"`
+		} `query:".table"`
+		Fine float64 `query:".size"`
+		Both string  `query:".(name,size)"`
+	}
+	err := Load(doc, &s)
+	var errs LoadError
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected load error, got %v", err)
+	}
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	want := []string{"Name", "Size", "Missing", "Port", "Bad", "Table.TLS", "Both"}
+	if !reflect.DeepEqual(want, fields) {
+		t.Errorf("fields mismatched! want %v, got %v", want, fields)
+	}
+	if !errors.Is(errs[2], ErrMissing) {
+		t.Errorf("missing field should report ErrMissing, got %v", errs[2])
+	}
+	if !errors.Is(errs[6], ErrAmbiguous) {
+		t.Errorf("field with many results should report ErrAmbiguous, got %v", errs[6])
+	}
+	if s.Fine != 300 {
+		t.Errorf("valid fields should be loaded, got %v", s.Fine)
+	}
+	if err := Load(doc, s); err == nil {
+		t.Errorf("loading into a non pointer should fail")
+	}
+}
+
+type testInner struct {
+	Port int64 `query:".port"`
+}
+
+func TestLoadUnexportedEmbedded(t *testing.T) {
+	var tagged struct {
+		testInner `query:".server"`
+		Name      string `query:".name"`
+	}
+	doc := map[string]interface{}{
+		"name":   "foobar",
+		"server": Null{},
+		"port":   int64(80),
+	}
+	if err := Load(doc, &tagged); err != nil {
+		t.Fatalf("fail to load: %s", err)
+	}
+	if tagged.Name != "foobar" || tagged.Port != 0 {
+		t.Errorf("unexpected values: %+v", tagged)
+	}
+
+	var untagged struct {
+		testInner
+	}
+	if err := Load(doc, &untagged); err != nil {
+		t.Fatalf("fail to load: %s", err)
+	}
+	if untagged.Port != 80 {
+		t.Errorf("port: want 80, got %d", untagged.Port)
+	}
+}
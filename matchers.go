@@ -24,9 +24,9 @@ func castError(k string, v interface{}) error {
 }
 
 type Infix struct {
-	left  Matcher
-	right Matcher
-	op    rune
+	Left  Matcher
+	Right Matcher
+	Op    rune
 }
 
 func (i Infix) Match(doc map[string]interface{}) (bool, error) {
@@ -35,13 +35,13 @@ func (i Infix) Match(doc map[string]interface{}) (bool, error) {
 		right bool
 		err   error
 	)
-	if left, err = i.left.Match(doc); err != nil {
+	if left, err = i.Left.Match(doc); err != nil {
 		return left, err
 	}
-	if right, err = i.right.Match(doc); err != nil {
+	if right, err = i.Right.Match(doc); err != nil {
 		return right, err
 	}
-	switch i.op {
+	switch i.Op {
 	case TokAnd:
 		return left && right, nil
 	case TokOr:
@@ -51,35 +51,59 @@ func (i Infix) Match(doc map[string]interface{}) (bool, error) {
 	}
 }
 
+func (i Infix) String() string {
+	left := i.Left.String()
+	if _, ok := i.Left.(Infix); ok {
+		left = "(" + left + ")"
+	}
+	return fmt.Sprintf("%s %s %s", left, operators[i.Op], i.Right)
+}
+
 type Has struct {
-	option string
+	Option string
 }
 
 func (h Has) Match(doc map[string]interface{}) (bool, error) {
-	_, ok := doc[h.option]
+	_, ok := doc[h.Option]
 	return ok, nil
 }
 
+func (h Has) String() string {
+	return formatKey(h.Option)
+}
+
 type Expr struct {
-	option string
-	eval   Func
-	value  interface{}
-	op     rune
+	Option string
+	Call   *Call
+	Value  interface{}
+	Op     rune
+}
+
+func (e Expr) String() string {
+	var b strings.Builder
+	b.WriteString(formatKey(e.Option))
+	if e.Call != nil {
+		b.WriteString("." + e.Call.String())
+	}
+	b.WriteString(" " + operators[e.Op] + " ")
+	b.WriteString(formatValue(e.Value, e.Op))
+	return b.String()
 }
 
 func (e Expr) Match(doc map[string]interface{}) (bool, error) {
-	value, ok := doc[e.option]
+	value, ok := doc[e.Option]
 	if !ok {
-		return ok, fmt.Errorf("%w: %s", ErrNotFound, e.option)
+		return ok, fmt.Errorf("%w: %s", ErrNotFound, e.Option)
 	}
+	value = normalize(value)
 	var err error
-	if e.eval != nil {
-		value, err = e.eval(value)
+	if e.Call != nil {
+		value, err = e.Call.Eval(value)
 		if err != nil {
 			return false, err
 		}
 	}
-	switch es := e.value.(type) {
+	switch es := e.Value.(type) {
 	case []interface{}:
 		for i := range es {
 			if ok, err = e.test(es[i], value); ok {
@@ -87,16 +111,16 @@ func (e Expr) Match(doc map[string]interface{}) (bool, error) {
 			}
 		}
 	default:
-		ok, err = e.test(e.value, value)
+		ok, err = e.test(e.Value, value)
 	}
 	if err != nil {
-		return false, fmt.Errorf("%s: %w", e.option, err)
+		return false, fmt.Errorf("%s: %w", e.Option, err)
 	}
 	return ok, nil
 }
 
 func (e Expr) test(want, got interface{}) (bool, error) {
-	switch e.op {
+	switch e.Op {
 	case TokMatch:
 		return isMatch(want, got)
 	case TokEqual:
@@ -117,25 +141,17 @@ func (e Expr) test(want, got interface{}) (bool, error) {
 		}
 		return eq || le, nil
 	case TokGreater:
-		eq, err := isEqual(want, got)
-		if err != nil {
-			return eq, err
-		}
-		le, err := isLess(want, got)
-		if err != nil {
-			return le, err
-		}
-		return !eq && !le, nil
+		return isLess(got, want)
 	case TokGreatEq:
 		eq, err := isEqual(want, got)
 		if err != nil {
 			return eq, err
 		}
-		le, err := isLess(want, got)
+		gt, err := isLess(got, want)
 		if err != nil {
-			return le, err
+			return gt, err
 		}
-		return eq || !le, nil
+		return eq || gt, nil
 	case TokContains:
 		return contains(want, got)
 	case TokStartsWith:
@@ -148,10 +164,11 @@ func (e Expr) test(want, got interface{}) (bool, error) {
 }
 
 func isMatch(want, got interface{}) (bool, error) {
-	var (
-		pat = want.(string)
-		str string
-	)
+	pat, ok := want.(string)
+	if !ok {
+		return false, castError("string", want)
+	}
+	var str string
 	switch v := got.(type) {
 	case int64:
 		str = strconv.FormatInt(v, 10)
@@ -206,6 +223,13 @@ func endsWith(want, got interface{}) (bool, error) {
 }
 
 func isEqual(want, got interface{}) (bool, error) {
+	if isNull(want) || isNull(got) {
+		return isNull(want) && isNull(got), nil
+	}
+	if isNumber(want) && isNumber(got) {
+		cmp, ok := compareNumbers(got, want)
+		return ok && cmp == 0, nil
+	}
 	switch val := got.(type) {
 	case string:
 		other, ok := want.(string)
@@ -213,18 +237,8 @@ func isEqual(want, got interface{}) (bool, error) {
 			return false, castError("string", want)
 		}
 		return val == other, nil
-	case int64:
-		other, ok := want.(int64)
-		if !ok {
-			return false, castError("integer", want)
-		}
-		return val == other, nil
-	case float64:
-		other, ok := want.(float64)
-		if !ok {
-			return false, castError("float", want)
-		}
-		return val == other, nil
+	case int64, float64:
+		return false, castError("number", want)
 	case bool:
 		other, ok := want.(bool)
 		if !ok {
@@ -242,6 +256,13 @@ func isEqual(want, got interface{}) (bool, error) {
 }
 
 func isLess(want, got interface{}) (bool, error) {
+	if isNull(want) || isNull(got) {
+		return false, nil
+	}
+	if isNumber(want) && isNumber(got) {
+		cmp, ok := compareNumbers(got, want)
+		return ok && cmp < 0, nil
+	}
 	switch val := got.(type) {
 	case string:
 		other, ok := want.(string)
@@ -249,18 +270,8 @@ func isLess(want, got interface{}) (bool, error) {
 			return false, castError("string", want)
 		}
 		return strings.Compare(val, other) < 0, nil
-	case int64:
-		other, ok := want.(int64)
-		if !ok {
-			return false, castError("integer", want)
-		}
-		return val < other, nil
-	case float64:
-		other, ok := want.(float64)
-		if !ok {
-			return false, castError("float", want)
-		}
-		return val < other, nil
+	case int64, float64:
+		return false, castError("number", want)
 	case bool:
 		return false, fmt.Errorf("booleans can only be compared for equality")
 	case time.Time:
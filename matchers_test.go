@@ -1,9 +1,118 @@
 package query
 
 import (
+	"math"
 	"testing"
 )
 
 func TestMatcher(t *testing.T) {
 	t.SkipNow()
 }
+
+func TestCompareNumbers(t *testing.T) {
+	data := []struct {
+		Op   rune
+		Want interface{}
+		Got  interface{}
+		Ok   bool
+	}{
+		{Op: TokGreater, Want: int64(1), Got: 1.5, Ok: true},
+		{Op: TokLesser, Want: int64(1), Got: 1.5, Ok: false},
+		{Op: TokEqual, Want: int64(2), Got: 2.0, Ok: true},
+		{Op: TokEqual, Want: 2.0, Got: int64(2), Ok: true},
+		{Op: TokNotEqual, Want: 2.5, Got: int64(2), Ok: true},
+		{Op: TokGreatEq, Want: 2.0, Got: int64(2), Ok: true},
+		{Op: TokLessEq, Want: -0.5, Got: int64(-1), Ok: true},
+		{Op: TokLesser, Want: int64(math.MaxInt64), Got: float64(math.MaxInt64), Ok: false},
+		{Op: TokGreater, Want: int64(math.MaxInt64), Got: float64(math.MaxInt64), Ok: true},
+		{Op: TokEqual, Want: int64(1<<53 + 1), Got: float64(1 << 53), Ok: false},
+		{Op: TokLesser, Want: int64(1<<53 + 1), Got: float64(1 << 53), Ok: true},
+		{Op: TokLesser, Want: math.Inf(1), Got: int64(math.MaxInt64), Ok: true},
+		{Op: TokGreater, Want: math.Inf(-1), Got: int64(math.MinInt64), Ok: true},
+		{Op: TokLesser, Want: int64(math.MinInt64), Got: math.Inf(-1), Ok: true},
+		{Op: TokEqual, Want: math.NaN(), Got: math.NaN(), Ok: false},
+		{Op: TokNotEqual, Want: math.NaN(), Got: int64(1), Ok: true},
+		{Op: TokGreater, Want: int64(0), Got: math.NaN(), Ok: false},
+		{Op: TokGreatEq, Want: int64(0), Got: math.NaN(), Ok: false},
+		{Op: TokLesser, Want: math.NaN(), Got: 1.0, Ok: false},
+	}
+	for _, d := range data {
+		e := Expr{
+			Option: "value",
+			Value:  d.Want,
+			Op:     d.Op,
+		}
+		ok, err := e.Match(map[string]interface{}{"value": d.Got})
+		if err != nil {
+			t.Errorf("%v %s %v: unexpected error: %s", d.Got, Token{Type: d.Op}, d.Want, err)
+			continue
+		}
+		if ok != d.Ok {
+			t.Errorf("%v %s %v: want %t, got %t", d.Got, Token{Type: d.Op}, d.Want, d.Ok, ok)
+		}
+	}
+}
+
+func TestCompareNumbersQuery(t *testing.T) {
+	doc := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "foo", "ratio": 1.5},
+			map[string]interface{}{"name": "bar", "ratio": int64(1)},
+			map[string]interface{}{"name": "baz", "ratio": 0.5},
+		},
+	}
+	data := map[string]int{
+		".items[ratio > 1]":                   1,
+		".items[ratio >= 1]":                  2,
+		".items[ratio == 1.0]":                1,
+		".items[ratio < inf && ratio > -inf]": 3,
+		".items[ratio == nan]":                0,
+		".items[ratio.pow(2) >= 1]":           2,
+		".items[name.length == 3]":            3,
+		".items[ratio == (0.5, 1)]":           2,
+	}
+	for str, want := range data {
+		q, err := Parse(str)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", str, err)
+			continue
+		}
+		rs, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", str, err)
+			continue
+		}
+		if len(rs) != want {
+			t.Errorf("%s: want %d results, got %d", str, want, len(rs))
+		}
+	}
+}
+
+func TestFuncsInvalid(t *testing.T) {
+	doc := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "foo", "size": int64(8)},
+		},
+	}
+	data := map[string]bool{
+		".items[size.lshift(-1) == 0]":           true,
+		".items[size.rshift(-1) == 0]":           true,
+		".items[size.lshift(70) == 0]":           false,
+		".items[name.ltrim('', false) == 'foo']": false,
+		".items[name.rtrim('', true) == 'foo']":  false,
+	}
+	for str, fail := range data {
+		q, err := Parse(str)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", str, err)
+			continue
+		}
+		_, err = q.Select(doc)
+		if fail && err == nil {
+			t.Errorf("%s: expected error", str)
+		}
+		if !fail && err != nil {
+			t.Errorf("%s: unexpected error: %s", str, err)
+		}
+	}
+}
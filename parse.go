@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -43,17 +44,21 @@ func NewParser(str string) *Parser {
 	var p Parser
 	p.scan = NewScanner(str)
 	p.selectors = map[rune]func(rune) (Selector, error){
-		TokSelectFirst:  p.parseSelectSimple,
-		TokSelectLast:   p.parseSelectSimple,
-		TokSelectInt:    p.parseSelectSimple,
-		TokSelectFloat:  p.parseSelectSimple,
-		TokSelectNumber: p.parseSelectSimple,
-		TokSelectBool:   p.parseSelectSimple,
-		TokSelectString: p.parseSelectSimple,
-		TokSelectTruthy: p.parseSelectSimple,
-		TokSelectFalsy:  p.parseSelectSimple,
-		TokSelectAt:     p.parseSelectAt,
-		TokSelectRange:  p.parseSelectRange,
+		TokSelectFirst:   p.parseSelectSimple,
+		TokSelectLast:    p.parseSelectSimple,
+		TokSelectInt:     p.parseSelectSimple,
+		TokSelectFloat:   p.parseSelectSimple,
+		TokSelectNumber:  p.parseSelectSimple,
+		TokSelectBool:    p.parseSelectSimple,
+		TokSelectString:  p.parseSelectSimple,
+		TokSelectTruthy:  p.parseSelectSimple,
+		TokSelectFalsy:   p.parseSelectSimple,
+		TokSelectNull:    p.parseSelectSimple,
+		TokSelectKeys:    p.parseSelectSimple,
+		TokSelectValues:  p.parseSelectSimple,
+		TokSelectEntries: p.parseSelectSimple,
+		TokSelectAt:      p.parseSelectAt,
+		TokSelectRange:   p.parseSelectRange,
 	}
 	p.next()
 	p.next()
@@ -74,10 +79,12 @@ func (p *Parser) parse() (Queryer, error) {
 		}
 		qs = append(qs, q)
 		switch p.curr.Type {
+		case TokIllegal:
+			return nil, fmt.Errorf("parse: illegal token %s", p.curr)
 		case TokComma:
 			p.next()
 			switch {
-			case p.curr.isKey() || p.curr.isLevel() || p.curr.isType():
+			case p.curr.isKey() || p.curr.isLevel() || p.curr.isType() || p.curr.isWildcard():
 			default:
 				return nil, fmt.Errorf("parse: unexpected token %s", p.curr)
 			}
@@ -100,22 +107,25 @@ func (p *Parser) parse() (Queryer, error) {
 
 func (p *Parser) parseQuery() (Queryer, error) {
 	var q Query
-	q.depth = TokLevelAny
+	q.Depth = TokLevelAny
 	if p.curr.isLevel() {
-		q.depth = p.curr.Type
+		q.Depth = p.curr.Type
 		p.next()
 	}
 	choices, err := p.parseChoices()
 	if err != nil {
 		return nil, err
 	}
-	q.choices = choices
+	q.Choices = choices
+	if _, ok := q.wildcard(); ok && q.Depth != TokLevelOne {
+		return nil, fmt.Errorf("choices: %s only selects in the current table", q.Choices[0])
+	}
 	if p.curr.isSelector() {
 		get, err := p.parseSelector()
 		if err != nil {
 			return nil, err
 		}
-		q.get = get
+		q.Get = get
 	}
 	if p.curr.isExpression() {
 		p.next()
@@ -123,35 +133,74 @@ func (p *Parser) parseQuery() (Queryer, error) {
 		if err != nil {
 			return nil, err
 		}
-		q.match = match
+		q.Match = match
 	}
-	if p.curr.isLevel() {
-		qs, err := p.parseQuery()
+	if p.curr.isLevel() || p.curr.isAxis() {
+		qs, err := p.parseNext()
 		if err != nil {
 			return nil, err
 		}
-		q.next = qs
+		q.Next = qs
 	}
 	return q, nil
 }
 
+func (p *Parser) parseNext() (Queryer, error) {
+	if p.curr.isAxis() {
+		return p.parseAxis()
+	}
+	return p.parseQuery()
+}
+
+func (p *Parser) parseAxis() (Queryer, error) {
+	a := Axis{Depth: p.curr.Type}
+	p.next()
+	if p.curr.isExpression() {
+		p.next()
+		match, err := p.parseMatcher()
+		if err != nil {
+			return nil, err
+		}
+		a.Match = match
+	}
+	if p.curr.isLevel() || p.curr.isAxis() {
+		qs, err := p.parseNext()
+		if err != nil {
+			return nil, err
+		}
+		a.Next = qs
+	}
+	return a, nil
+}
+
 func (p *Parser) parseChoices() ([]Accepter, error) {
 	var kind rune
 	if p.curr.isType() {
 		kind = p.curr.Type
 		p.next()
 	}
+	switch p.curr.Type {
+	case TokWildcard:
+		p.next()
+		return []Accepter{Wildcard{Kind: kind}}, nil
+	case TokGlobstar:
+		if kind != 0 {
+			return nil, fmt.Errorf("choices: unexpected token %s, want identifier", p.curr)
+		}
+		p.next()
+		return []Accepter{Globstar{}}, nil
+	}
 	if p.curr.isKey() {
 		var a Accepter
 		if p.curr.Type == TokPattern {
 			a = Pattern{
-				pattern: p.curr.Literal,
-				kind:    kind,
+				Pattern: p.curr.Literal,
+				Kind:    kind,
 			}
 		} else {
 			a = Name{
-				label: p.curr.Literal,
-				kind:  kind,
+				Label: p.curr.Literal,
+				Kind:  kind,
 			}
 		}
 		p.next()
@@ -169,13 +218,13 @@ func (p *Parser) parseChoices() ([]Accepter, error) {
 		var a Accepter
 		if p.curr.Type == TokPattern {
 			a = Pattern{
-				pattern: p.curr.Literal,
-				kind:    kind,
+				Pattern: p.curr.Literal,
+				Kind:    kind,
 			}
 		} else {
 			a = Name{
-				label: p.curr.Literal,
-				kind:  kind,
+				Label: p.curr.Literal,
+				Kind:  kind,
 			}
 		}
 		choices = append(choices, a)
@@ -215,15 +264,15 @@ func (p *Parser) parseMatcher() (Matcher, error) {
 	}
 	if p.curr.isRelation() {
 		i := Infix{
-			op:   p.curr.Type,
-			left: left,
+			Op:   p.curr.Type,
+			Left: left,
 		}
 		p.next()
 		right, err := p.parseMatcher()
 		if err != nil {
 			return nil, err
 		}
-		i.right = right
+		i.Right = right
 		return i, nil
 	}
 	switch p.curr.Type {
@@ -236,14 +285,14 @@ func (p *Parser) parseMatcher() (Matcher, error) {
 	return left, err
 }
 
-func (p *Parser) parseEval() (Func, error) {
+func (p *Parser) parseEval() (*Call, error) {
 	p.next()
 	if p.curr.Type != TokLiteral {
 		return nil, fmt.Errorf("eval: unexpected token %s, want identifier", p.curr)
 	}
-	fn, ok := funcnames[p.curr.Literal]
-	if !ok {
-		return nil, fmt.Errorf("eval: unknown function %q", p.curr.Literal)
+	name := p.curr.Literal
+	if _, ok := funcnames[name]; !ok {
+		return nil, fmt.Errorf("eval: unknown function %q", name)
 	}
 	p.next()
 	var args []interface{}
@@ -272,7 +321,11 @@ func (p *Parser) parseEval() (Func, error) {
 		}
 		p.next()
 	}
-	return fn(args), nil
+	call, err := makeCall(name, args)
+	if err != nil {
+		return nil, fmt.Errorf("eval: %w", err)
+	}
+	return &call, nil
 }
 
 func (p *Parser) parseExpression() (Matcher, error) {
@@ -282,38 +335,38 @@ func (p *Parser) parseExpression() (Matcher, error) {
 	}
 	var (
 		ident = p.curr
-		eval  Func
+		call  *Call
 	)
 	p.next()
 	if p.curr.Type == TokLevelOne {
-		fn, err := p.parseEval()
+		c, err := p.parseEval()
 		if err != nil {
 			return nil, err
 		}
 		if !p.curr.isComparison() {
 			return nil, fmt.Errorf("expr: unexpected token %s, want 'cmp' after function call", p.curr)
 		}
-		eval = fn
+		call = c
 	}
 	if p.curr.isComparison() {
 		e := Expr{
-			option: ident.Literal,
-			op:     p.curr.Type,
-			eval:   eval,
+			Option: ident.Literal,
+			Op:     p.curr.Type,
+			Call:   call,
 		}
 		p.next()
 		if !p.curr.isValue() && p.curr.Type != TokBegGrp {
 			return nil, fmt.Errorf("expr: unexpected token %s, want value", p.curr)
 		}
-		value, err := p.parseValue(e.op)
+		value, err := p.parseValue(e.Op)
 		if err != nil {
 			return nil, fmt.Errorf("expr(value): %w", err)
 		}
-		e.value = value
+		e.Value = value
 		left = e
 		p.next()
 	} else {
-		left = Has{option: ident.Literal}
+		left = Has{Option: ident.Literal}
 	}
 	return left, nil
 }
@@ -350,8 +403,14 @@ func (p *Parser) convertValue() (interface{}, error) {
 		val = p.curr.Literal
 	case TokBool:
 		val, err = strconv.ParseBool(p.curr.Literal)
+	case TokNull:
+		val = Null{}
 	case TokFloat:
-		val, err = strconv.ParseFloat(p.curr.Literal, 64)
+		str := strings.TrimLeft(p.curr.Literal, "+-")
+		if str != "nan" {
+			str = p.curr.Literal
+		}
+		val, err = strconv.ParseFloat(str, 64)
 	case TokInteger:
 		val, err = strconv.ParseInt(p.curr.Literal, 0, 64)
 	case TokTime:
@@ -447,6 +506,14 @@ func (p *Parser) parseSelectSimple(curr rune) (Selector, error) {
 		get = Truthy{}
 	case TokSelectFalsy:
 		get = Falsy{}
+	case TokSelectNull:
+		get = IsNull{}
+	case TokSelectKeys:
+		get = Keys{}
+	case TokSelectValues:
+		get = Values{}
+	case TokSelectEntries:
+		get = Entries{}
 	default:
 		err = fmt.Errorf("selector: unsupported token %s", p.curr)
 	}
@@ -459,11 +526,14 @@ func (p *Parser) parseSelectAt(_ rune) (Selector, error) {
 		return nil, fmt.Errorf("at: unexpected token %s, want lparen", p.curr)
 	}
 	p.next()
+	if p.curr.Type != TokInteger {
+		return nil, fmt.Errorf("at: unexpected token %s, want integer", p.curr)
+	}
 	ix, err := strconv.ParseInt(p.curr.Literal, 0, 64)
 	if err != nil {
 		return nil, fmt.Errorf("at: %w", err)
 	}
-	at.index = int(ix)
+	at.Index = int(ix)
 
 	p.next()
 	if p.curr.Type != TokEndGrp {
@@ -484,7 +554,7 @@ func (p *Parser) parseSelectRange(_ rune) (Selector, error) {
 		if err != nil {
 			return nil, fmt.Errorf("range: %w", err)
 		}
-		rg.start = int(ix)
+		rg.Start = int(ix)
 		p.next()
 	}
 	if p.curr.Type != TokComma {
@@ -496,7 +566,7 @@ func (p *Parser) parseSelectRange(_ rune) (Selector, error) {
 		if err != nil {
 			return nil, err
 		}
-		rg.end = int(ix)
+		rg.End = int(ix)
 		p.next()
 	}
 	if p.curr.Type != TokEndGrp {
@@ -95,7 +95,7 @@ func TestParse(t *testing.T) {
 			Choices: []Accepter{
 				createPattern("[a-zA-Z]?*", TokArray),
 			},
-			Selector: Range{start: 0, end: 10},
+			Selector: Range{Start: 0, End: 10},
 		},
 		{
 			Input: "..@foo:range(, 10)",
@@ -103,7 +103,7 @@ func TestParse(t *testing.T) {
 			Choices: []Accepter{
 				createName("foo", TokArray),
 			},
-			Selector: Range{start: 0, end: 10},
+			Selector: Range{Start: 0, End: 10},
 		},
 		{
 			Input: "..@foo:range(2,)",
@@ -111,7 +111,7 @@ func TestParse(t *testing.T) {
 			Choices: []Accepter{
 				createName("foo", TokArray),
 			},
-			Selector: Range{start: 2, end: 0},
+			Selector: Range{Start: 2, End: 0},
 		},
 		{
 			Input: ".foo..bar[str]",
@@ -247,12 +247,64 @@ func TestParse(t *testing.T) {
 			},
 			Matcher: createExpr(TokMatch, "pat", []interface{}{"[a-z][0-9]*", "[A-Z][a-z].???"}),
 		},
+		{
+			Input: "foo[opt == null].bar:null",
+			Depth: TokLevelAny,
+			Choices: []Accepter{
+				createName("foo", 0),
+			},
+			Matcher: createExpr(TokEqual, "opt", Null{}),
+			Next: &ParseCase{
+				Depth: TokLevelOne,
+				Choices: []Accepter{
+					createName("bar", 0),
+				},
+				Selector: IsNull{},
+			},
+		},
 	}
 	for _, d := range data {
 		testQuery(t, d)
 	}
 }
 
+func TestParseInvalid(t *testing.T) {
+	data := []string{
+		"foo:at(-1)",
+		"foo:at(bar)",
+		"foo:at(",
+		"foo:range(1",
+		"foo\xff",
+		"foo.\xffbar",
+		"foo[bar",
+		"foo[bar ==",
+		"foo[bar.lshift(",
+		"[",
+		")",
+		"foo,",
+		"foo:unknown",
+	}
+	for _, str := range data {
+		if _, err := Parse(str); err == nil {
+			t.Errorf("%q: expected error but query parsed", str)
+		}
+	}
+}
+
+func TestParseCall(t *testing.T) {
+	q, err := Parse("foo[bar.lshift(2) == 8]")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	c := q.(Query).Match.(Expr).Call
+	if c == nil || c.fn != funcnames["lshift"] {
+		t.Fatalf("lshift: function not resolved when parsed")
+	}
+	if v, err := c.Eval(int64(2)); err != nil || v != int64(8) {
+		t.Fatalf("lshift: want 8, got %v (%v)", v, err)
+	}
+}
+
 func testQuery(t *testing.T, pc ParseCase) {
 	t.Helper()
 	q, err := Parse(pc.Input)
@@ -279,56 +331,56 @@ func testQuery(t *testing.T, pc ParseCase) {
 
 func testSimpleQuery(t *testing.T, q Query, pc ParseCase) {
 	t.Helper()
-	if q.depth != pc.Depth {
-		t.Errorf("%s: depth mismatched! want %02x, got %02x", pc.Input, pc.Depth, q.depth)
+	if q.Depth != pc.Depth {
+		t.Errorf("%s: depth mismatched! want %02x, got %02x", pc.Input, pc.Depth, q.Depth)
 	}
-	if !reflect.DeepEqual(q.choices, pc.Choices) {
-		t.Errorf("%s: choices mismatched! want %v, got %v", pc.Input, pc.Choices, q.choices)
+	if !reflect.DeepEqual(q.Choices, pc.Choices) {
+		t.Errorf("%s: choices mismatched! want %v, got %v", pc.Input, pc.Choices, q.Choices)
 	}
-	if !reflect.DeepEqual(q.get, pc.Selector) {
-		t.Errorf("%s: selectors mismatched! want %v, got %v", pc.Input, pc.Selector, q.get)
+	if !reflect.DeepEqual(q.Get, pc.Selector) {
+		t.Errorf("%s: selectors mismatched! want %v, got %v", pc.Input, pc.Selector, q.Get)
 	}
-	if !reflect.DeepEqual(q.match, pc.Matcher) {
+	if !reflect.DeepEqual(q.Match, pc.Matcher) {
 		t.Errorf("%s: matchers mismatched!", pc.Input)
 		t.Logf("\twant: %v", pc.Matcher)
-		t.Logf("\tgot:  %v", q.match)
+		t.Logf("\tgot:  %v", q.Match)
 	}
-	if q, ok := q.next.(Query); ok && pc.Next != nil {
+	if q, ok := q.Next.(Query); ok && pc.Next != nil {
 		pc.Next.Input = pc.Input
 		testSimpleQuery(t, q, *pc.Next)
 	}
 }
 
 func createExist(str string) Matcher {
-	return Has{option: str}
+	return Has{Option: str}
 }
 
 func createExpr(op rune, str string, value interface{}) Matcher {
 	return Expr{
-		option: str,
-		value:  value,
-		op:     op,
+		Option: str,
+		Value:  value,
+		Op:     op,
 	}
 }
 
 func createInfix(op rune, left, right Matcher) Matcher {
 	return Infix{
-		left:  left,
-		right: right,
-		op:    op,
+		Left:  left,
+		Right: right,
+		Op:    op,
 	}
 }
 
 func createPattern(str string, kind rune) Accepter {
 	return Pattern{
-		pattern: str,
-		kind:    kind,
+		Pattern: str,
+		Kind:    kind,
 	}
 }
 
 func createName(str string, kind rune) Accepter {
 	return Name{
-		label: str,
-		kind:  kind,
+		Label: str,
+		Kind:  kind,
 	}
 }
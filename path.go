@@ -0,0 +1,309 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type Segment struct {
+	Key   string
+	Index int
+	index bool
+}
+
+func Key(key string) Segment {
+	return Segment{Key: key}
+}
+
+func Index(index int) Segment {
+	return Segment{Index: index, index: true}
+}
+
+func (s Segment) IsIndex() bool {
+	return s.index
+}
+
+func (s Segment) String() string {
+	if s.index {
+		return "[" + strconv.Itoa(s.Index) + "]"
+	}
+	return quoteKey(s.Key)
+}
+
+type Path []Segment
+
+func (p Path) String() string {
+	var b strings.Builder
+	for i, s := range p {
+		if i > 0 && !s.index {
+			b.WriteRune(dot)
+		}
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+func (p Path) Pointer() string {
+	var b strings.Builder
+	for _, s := range p {
+		b.WriteRune(slash)
+		if s.index {
+			b.WriteString(strconv.Itoa(s.Index))
+			continue
+		}
+		str := strings.ReplaceAll(s.Key, "~", "~0")
+		b.WriteString(strings.ReplaceAll(str, "/", "~1"))
+	}
+	return b.String()
+}
+
+func (p Path) Keys() []string {
+	ks := make([]string, 0, len(p))
+	for _, s := range p {
+		if !s.index {
+			ks = append(ks, s.Key)
+		}
+	}
+	return ks
+}
+
+func (p Path) Resolve(doc interface{}) (interface{}, error) {
+	for i, s := range p {
+		if s.index {
+			arr, ok := normalize(doc).([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: array expected", p[:i+1])
+			}
+			if s.Index < 0 || s.Index >= len(arr) {
+				return nil, fmt.Errorf("%s: index out of range", p[:i+1])
+			}
+			doc = arr[s.Index]
+			continue
+		}
+		tab, ok := normalize(doc).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: table expected", p[:i+1])
+		}
+		if doc, ok = tab[s.Key]; !ok {
+			return nil, fmt.Errorf("%s: %w", p[:i+1], ErrNotFound)
+		}
+	}
+	return doc, nil
+}
+
+// trail tracks the position of the query while it walks a document. The
+// segments are pushed and popped on a single stack shared by the whole walk
+// and only emitted results copy them into a Path of their own.
+type trail struct {
+	root   interface{}
+	segs   []Segment
+	limits *limits
+	trace  *tracer
+	index  *KeyIndex
+	moved  map[string]struct{}
+	refs   map[ref]struct{}
+	yield  func(Result) bool
+}
+
+// errStop aborts the walk when the receiver of the results does not want
+// more of them.
+var errStop = errors.New("stop")
+
+func stopped(err error) error {
+	if err == errStop {
+		return nil
+	}
+	return err
+}
+
+func (t *trail) push(seg Segment) error {
+	t.segs = append(t.segs, seg)
+	if t.limits == nil {
+		return nil
+	}
+	return t.limits.visit(len(t.segs))
+}
+
+func (t *trail) emit(n int) error {
+	if t.limits == nil {
+		return nil
+	}
+	return t.limits.emit(n)
+}
+
+func (t *trail) send(r Result) error {
+	if err := t.emit(1); err != nil {
+		return err
+	}
+	if t.yield != nil && !t.yield(r) {
+		return errStop
+	}
+	return nil
+}
+
+func (t *trail) pop() {
+	t.segs = t.segs[:len(t.segs)-1]
+}
+
+// move reports whether the axis or the globstar identified by id has not
+// already moved to the position p during the evaluation of the query and
+// records that it has.
+func (t *trail) move(id string, p Path) bool {
+	if t.moved == nil {
+		t.moved = make(map[string]struct{})
+	}
+	key := id + "\x00" + p.String()
+	if _, ok := t.moved[key]; ok {
+		return false
+	}
+	t.moved[key] = struct{}{}
+	return true
+}
+
+// enter reports whether ifi does not refer to the document or to one of the
+// values holding it on the current path and records it until leave is called.
+// The walks skip the values referring back to one of their parents, so that a
+// cyclic Go value is only walked once.
+func (t *trail) enter(ifi interface{}) bool {
+	r, ok := refOf(ifi)
+	if !ok {
+		return true
+	}
+	if _, ok := t.refs[r]; ok {
+		return false
+	}
+	if root, ok := refOf(t.root); ok && root == r {
+		return false
+	}
+	if t.refs == nil {
+		t.refs = make(map[ref]struct{})
+	}
+	t.refs[r] = struct{}{}
+	return true
+}
+
+func (t *trail) leave(ifi interface{}) {
+	if r, ok := refOf(ifi); ok {
+		delete(t.refs, r)
+	}
+}
+
+// ref identifies the value a pointer, a map or a slice refers to.
+type ref struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+func refOf(ifi interface{}) (ref, bool) {
+	if ifi == nil {
+		return ref{}, false
+	}
+	v := reflect.ValueOf(ifi)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() || (v.Kind() != reflect.Ptr && v.Len() == 0) {
+			return ref{}, false
+		}
+		return ref{typ: v.Type(), ptr: v.Pointer()}, true
+	default:
+		return ref{}, false
+	}
+}
+
+func (t *trail) tracing() bool {
+	return t.trace != nil
+}
+
+// begin records a step that holds the steps recorded until the call to end.
+func (t *trail) begin(s Step) {
+	if t.trace == nil {
+		return
+	}
+	s.Path = t.Path()
+	t.trace.begin(s)
+}
+
+func (t *trail) end() {
+	if t.trace == nil {
+		return
+	}
+	t.trace.end()
+}
+
+// note records a step at the current position or, when more segments are
+// given, at the position they lead to.
+func (t *trail) note(s Step, more ...Segment) {
+	if t.trace == nil {
+		return
+	}
+	s.Path = t.Path()
+	if len(more) > 0 {
+		s.Path = append(s.Path, more...)
+	}
+	t.trace.add(s)
+}
+
+func (t *trail) Path() Path {
+	if len(t.segs) == 0 {
+		return nil
+	}
+	ps := make(Path, len(t.segs))
+	copy(ps, t.segs)
+	return ps
+}
+
+func (t *trail) prepend(p Path) Path {
+	if len(t.segs) == 0 {
+		return p
+	}
+	ps := make(Path, 0, len(t.segs)+len(p))
+	ps = append(ps, t.segs...)
+	return append(ps, p...)
+}
+
+func quoteKey(key string) string {
+	bare := len(key) > 0
+	for _, r := range key {
+		if !isAlpha(r) {
+			bare = false
+			break
+		}
+	}
+	if bare {
+		return key
+	}
+	return quoteString(key)
+}
+
+func quoteString(str string) string {
+	var b strings.Builder
+	b.WriteRune(dquote)
+	for _, r := range str {
+		switch r {
+		case dquote, backslash:
+			b.WriteRune(backslash)
+			b.WriteRune(r)
+		case newline:
+			b.WriteString("\\n")
+		case tab:
+			b.WriteString("\\t")
+		case carriage:
+			b.WriteString("\\r")
+		case formfeed:
+			b.WriteString("\\f")
+		case backspace:
+			b.WriteString("\\b")
+		default:
+			if r < space || r == 0x7f {
+				fmt.Fprintf(&b, "\\u%04x", r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteRune(dquote)
+	return b.String()
+}
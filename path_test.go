@@ -0,0 +1,272 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	data := []struct {
+		Input string
+		Want  []string
+	}{
+		{
+			Input: ".service",
+			Want:  []string{"service"},
+		},
+		{
+			Input: "..addr",
+			Want: []string{
+				"client[0].addr",
+				"client[1].addr",
+				"client[2].addr",
+				"servers.backup.addr",
+				"servers.groups[0].addr",
+				"servers.groups[1].addr",
+				"servers.prime.addr",
+			},
+		},
+		{
+			Input: ".client[tls == true].addr",
+			Want: []string{
+				"client[1].addr",
+				"client[2].addr",
+			},
+		},
+		{
+			Input: "@groups:first.addr",
+			Want:  []string{"servers.groups[0].addr"},
+		},
+		{
+			Input: "@groups:last[mode].addr",
+			Want:  []string{"servers.groups[1].addr"},
+		},
+		{
+			Input: "@groups:last.addr",
+			Want:  []string{"servers.groups[1].addr"},
+		},
+		{
+			Input: ".client:at(2)",
+			Want:  []string{"client[2]"},
+		},
+		{
+			Input: "@groups:last",
+			Want:  []string{"servers.groups[1]"},
+		},
+		{
+			Input: ".client:range(1,)",
+			Want:  []string{"client[1]", "client[2]"},
+		},
+		{
+			Input: ".client:range(0,2)",
+			Want:  []string{"client[0]", "client[1]"},
+		},
+		{
+			Input: ".client:range(1,)[rps].cred.user",
+			Want: []string{
+				"client[1].cred.user",
+				"client[2].cred.user",
+			},
+		},
+		{
+			Input: "..cred.user",
+			Want: []string{
+				"client[0].cred.user",
+				"client[1].cred.user",
+				"client[2].cred.user",
+			},
+		},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		rs, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", d.Input, err)
+			continue
+		}
+		var got []string
+		for _, r := range rs {
+			got = append(got, r.Paths.String())
+			v, err := r.Paths.Resolve(doc)
+			if err != nil {
+				t.Errorf("%s: fail to resolve %s: %s", d.Input, r.Paths, err)
+				continue
+			}
+			if !reflect.DeepEqual(v, r.Value) {
+				t.Errorf("%s: %s resolved to %v, want %v", d.Input, r.Paths, v, r.Value)
+			}
+		}
+		if !reflect.DeepEqual(d.Want, got) {
+			t.Errorf("%s: paths mismatched! want %v, got %v", d.Input, d.Want, got)
+		}
+	}
+}
+
+func TestPathFormat(t *testing.T) {
+	p := Path{Key("servers"), Key("web one"), Key("a/b~c"), Index(2), Key("tls")}
+	if got, want := p.String(), `servers."web one"."a/b~c"[2].tls`; got != want {
+		t.Errorf("string mismatched! want %s, got %s", want, got)
+	}
+	if got, want := p.Pointer(), "/servers/web one/a~1b~0c/2/tls"; got != want {
+		t.Errorf("pointer mismatched! want %s, got %s", want, got)
+	}
+	if got := (Path{}).Pointer(); got != "" {
+		t.Errorf("pointer of empty path should be empty, got %s", got)
+	}
+	if _, err := p.Resolve(doc); err == nil {
+		t.Errorf("resolving unknown path should fail")
+	}
+}
+
+func TestPathsDeep(t *testing.T) {
+	tree := makeTree(6, 3)
+	q, err := Parse("..leaf")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	rs, err := q.Select(tree)
+	if err != nil {
+		t.Fatalf("fail to select: %s", err)
+	}
+	if want := countLeaves(6, 3); len(rs) != want {
+		t.Fatalf("results mismatched! want %d, got %d", want, len(rs))
+	}
+	seen := make(map[string]struct{})
+	for _, r := range rs {
+		str := r.Paths.String()
+		if _, ok := seen[str]; ok {
+			t.Errorf("%s: path already seen", str)
+		}
+		seen[str] = struct{}{}
+		if want := r.Paths[len(r.Paths)-2].Key; want != r.Value {
+			t.Errorf("%s: value mismatched! want %v, got %v", str, want, r.Value)
+		}
+		v, err := r.Paths.Resolve(tree)
+		if err != nil {
+			t.Errorf("%s: fail to resolve: %s", str, err)
+			continue
+		}
+		if v != r.Value {
+			t.Errorf("%s: resolved to %v, want %v", str, v, r.Value)
+		}
+	}
+}
+
+func TestPathsShared(t *testing.T) {
+	q, err := Parse("..addr")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	rs, err := q.Select(doc)
+	if err != nil {
+		t.Fatalf("fail to select: %s", err)
+	}
+	before := make([]string, len(rs))
+	for i, r := range rs {
+		before[i] = r.Paths.String()
+	}
+	for i := range rs {
+		if len(rs[i].Paths) > 0 {
+			rs[i].Paths[0] = Key("changed")
+		}
+		for j := i + 1; j < len(rs); j++ {
+			if got := rs[j].Paths.String(); got != before[j] {
+				t.Fatalf("%d: path modified by sibling! want %s, got %s", j, before[j], got)
+			}
+		}
+	}
+}
+
+func TestAcceptLowestKey(t *testing.T) {
+	tab := map[string]interface{}{
+		"k3": int64(3),
+		"k1": "one",
+		"k2": int64(2),
+		"x0": int64(0),
+	}
+	as := []Accepter{
+		Pattern{Pattern: "k?"},
+		Pattern{Pattern: "k?", Kind: TokValue},
+		Wildcard{},
+	}
+	for i := 0; i < 10; i++ {
+		for _, a := range as {
+			key, _, found, err := a.Accept(tab)
+			if err != nil || !found {
+				t.Fatalf("%s: key expected, got %v (%v)", a, found, err)
+			}
+			if key != "k1" {
+				t.Fatalf("%s: want k1, got %s", a, key)
+			}
+		}
+	}
+	key, _, _, _ := Wildcard{Kind: TokValue}.Accept(map[string]interface{}{"b": int64(1), "a": []interface{}{}})
+	if key != "b" {
+		t.Errorf("*: want b, got %s", key)
+	}
+}
+
+func BenchmarkSelectDeep(b *testing.B) {
+	benchmarkSelect(b, "..leaf", makeTree(10, 2))
+}
+
+func BenchmarkSelectWide(b *testing.B) {
+	benchmarkSelect(b, "..leaf", makeTree(2, 60))
+}
+
+func BenchmarkSelectPattern(b *testing.B) {
+	benchmarkSelect(b, "../l?af/", makeTree(2, 60))
+}
+
+func BenchmarkSelectLast(b *testing.B) {
+	benchmarkSelect(b, "..node:last", makeTree(8, 4))
+}
+
+func benchmarkSelect(b *testing.B, query string, doc interface{}) {
+	q, err := Parse(query)
+	if err != nil {
+		b.Fatalf("fail to parse: %s", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := q.Select(doc); err != nil {
+			b.Fatalf("fail to select: %s", err)
+		}
+	}
+}
+
+// makeTree builds a document where each table has a leaf with its own key
+// and a node array of width tables, nested depth times.
+func makeTree(depth, width int) map[string]interface{} {
+	return makeNode("root", depth, width)
+}
+
+func makeNode(name string, depth, width int) map[string]interface{} {
+	tab := map[string]interface{}{
+		name: map[string]interface{}{"leaf": name},
+	}
+	if depth == 0 {
+		return tab
+	}
+	var arr []interface{}
+	for i := 0; i < width; i++ {
+		arr = append(arr, makeNode(fmt.Sprintf("node-%d", i), depth-1, width))
+	}
+	tab["node"] = arr
+	return tab
+}
+
+func countLeaves(depth, width int) int {
+	n, c := 1, 1
+	for i := 0; i < depth; i++ {
+		c *= width
+		n += c
+	}
+	return n
+}
@@ -1,31 +1,35 @@
 package query
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 )
 
 type Result struct {
-	Paths []string
+	Paths Path
 	Value interface{}
 }
 
-func makeResult(ps []string, ifi interface{}) Result {
-	return Result {
+func makeResult(ps Path, ifi interface{}) Result {
+	return Result{
 		Paths: ps,
 		Value: ifi,
 	}
 }
 
 type Selector interface {
-	Select(interface{}) interface{}
+	Select(interface{}) (interface{}, bool)
+	fmt.Stringer
 }
 
 type Matcher interface {
 	Match(map[string]interface{}) (bool, error)
+	fmt.Stringer
 }
 
 type Accepter interface {
-	Accept(map[string]interface{}) (string, interface{}, error)
+	Accept(map[string]interface{}) (string, interface{}, bool, error)
 	fmt.Stringer
 }
 
@@ -33,189 +37,623 @@ type Queryer interface {
 	Select(interface{}) ([]Result, error)
 }
 
+// Eacher is implemented by the queries that give their results one at a time,
+// as soon as they are found. The walk of the document stops when fn returns
+// false.
+type Eacher interface {
+	Each(doc interface{}, fn func(Result) bool) error
+}
+
+// Each gives the results of q to fn until fn returns false. Queries that do
+// not implement Eacher give all their results to Select first.
+func Each(q Queryer, doc interface{}, fn func(Result) bool) error {
+	if e, ok := q.(Eacher); ok {
+		return e.Each(doc, fn)
+	}
+	rs, err := q.Select(doc)
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		if !fn(r) {
+			break
+		}
+	}
+	return nil
+}
+
 type Queryset []Queryer
 
 func (qs Queryset) Select(ifi interface{}) ([]Result, error) {
-	rs := make([]Result, 0, len(qs))
+	return collect(qs, ifi, trail{})
+}
+
+func (qs Queryset) Each(ifi interface{}, fn func(Result) bool) error {
+	return each(qs, ifi, fn)
+}
+
+func (qs Queryset) selectWith(ifi interface{}, at *trail) error {
+	if ws := qs.walkers(); ws != nil && at.index == nil && !at.tracing() {
+		return selectShared(ws, len(qs), ifi, at)
+	}
+	defer func(moved map[string]struct{}) {
+		at.moved = moved
+	}(at.moved)
 	for _, q := range qs {
-		r, err := q.Select(ifi)
-		if err != nil {
-			return nil, err
+		at.moved = nil
+		if err := selectWith(q, ifi, at); err != nil {
+			return err
 		}
-		rs = append(rs, r...)
+	}
+	return nil
+}
+
+func (qs Queryset) String() string {
+	strs := make([]string, len(qs))
+	for i := range qs {
+		strs[i] = fmt.Sprint(qs[i])
+	}
+	return strings.Join(strs, ",")
+}
+
+// collect gives all the results of q in a single slice.
+func collect(q Queryer, ifi interface{}, at trail) ([]Result, error) {
+	var rs []Result
+	at.yield = func(r Result) bool {
+		rs = append(rs, r)
+		return true
+	}
+	if x, ok := ifi.(*KeyIndex); ok {
+		at.index, ifi = x, x.doc
+	}
+	at.root = ifi
+	if err := selectWith(q, ifi, &at); err != nil {
+		return nil, err
 	}
 	return rs, nil
 }
 
+// each gives the results of q to fn as soon as they are found. The traversal
+// of ifi stops when fn returns false.
+func each(q Queryer, ifi interface{}, fn func(Result) bool) error {
+	at := trail{yield: fn}
+	if x, ok := ifi.(*KeyIndex); ok {
+		at.index, ifi = x, x.doc
+	}
+	at.root = ifi
+	return stopped(selectWith(q, ifi, &at))
+}
+
+func selectWith(q Queryer, ifi interface{}, at *trail) error {
+	switch q := q.(type) {
+	case Query:
+		if at.tracing() {
+			at.begin(Step{Kind: StepQuery, Node: q.head().String()})
+			defer at.end()
+		}
+		return q.selectFromInterface(ifi, at)
+	case Queryset:
+		return q.selectWith(ifi, at)
+	case Axis:
+		if at.tracing() {
+			at.begin(Step{Kind: StepQuery, Node: q.head().String()})
+			defer at.end()
+		}
+		return q.selectWith(at)
+	}
+	if at.tracing() {
+		at.begin(Step{Kind: StepQuery, Node: fmt.Sprint(q)})
+		defer at.end()
+	}
+	var err error
+	fail := Each(q, ifi, func(r Result) bool {
+		r.Paths = at.prepend(r.Paths)
+		err = at.send(r)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return fail
+}
+
 type Query struct {
-	choices []Accepter
-	depth   rune
-	match   Matcher
-	get     Selector
-	next    Queryer
+	Choices []Accepter
+	Depth   rune
+	Match   Matcher
+	Get     Selector
+	Next    Queryer
 }
 
 func (q Query) Select(ifi interface{}) ([]Result, error) {
-	return q.selectFromInterface(ifi)
+	return collect(q, ifi, trail{})
 }
 
-func (q Query) selectFromInterface(ifi interface{}) ([]Result, error) {
-	var (
-		err error
-		rs  []Result
-	)
-	switch is := ifi.(type) {
+func (q Query) Each(ifi interface{}, fn func(Result) bool) error {
+	return each(q, ifi, fn)
+}
+
+func (q Query) String() string {
+	var b strings.Builder
+	switch q.Depth {
+	case TokLevelOne:
+		b.WriteString(".")
+	case TokLevelGreedy:
+		b.WriteString("..!")
+	default:
+		b.WriteString("..")
+	}
+	if len(q.Choices) > 0 {
+		b.WriteString(formatKind(q.Choices[0]))
+	}
+	if len(q.Choices) == 1 {
+		b.WriteString(q.Choices[0].String())
+	} else {
+		strs := make([]string, len(q.Choices))
+		for i := range q.Choices {
+			strs[i] = q.Choices[i].String()
+		}
+		b.WriteString("(" + strings.Join(strs, ",") + ")")
+	}
+	if q.Get != nil {
+		b.WriteString(q.Get.String())
+	}
+	if q.Match != nil {
+		b.WriteString("[" + q.Match.String() + "]")
+	}
+	if q.Next != nil {
+		b.WriteString(fmt.Sprint(q.Next))
+	}
+	return b.String()
+}
+
+// head gives the query without its subquery.
+func (q Query) head() Query {
+	q.Next = nil
+	return q
+}
+
+// wildcard gives the key of q when it is * or **.
+func (q Query) wildcard() (Accepter, bool) {
+	if len(q.Choices) != 1 {
+		return nil, false
+	}
+	switch a := q.Choices[0].(type) {
+	case Wildcard, Globstar:
+		return a, true
+	default:
+		return nil, false
+	}
+}
+
+func (q Query) selectFromInterface(ifi interface{}, at *trail) error {
+	if a, ok := q.wildcard(); ok {
+		if g, ok := a.(Globstar); ok {
+			return q.globstar().selectGlobstar(g, q.String(), ifi, at)
+		}
+		return q.selectWildcard(a.(Wildcard), ifi, at)
+	}
+	switch is := normalize(ifi).(type) {
 	case []interface{}:
-		rs, err = q.selectFromArray(is)
+		return q.selectFromArray(is, at)
 	case map[string]interface{}:
-		rs, err = q.selectFromMap(is)
+		return q.selectFromMap(is, at)
 	default:
-		return nil, fmt.Errorf("query: can not select from %T", ifi)
+		return fmt.Errorf("query: can not select from %T", ifi)
 	}
-	return rs, err
 }
 
-func (q Query) selectFromArray(ifi []interface{}) ([]Result, error) {
-	var rs []Result
-	for _, i := range ifi {
-		js, err := q.selectFromInterface(i)
+func (q Query) selectFromArray(ifi []interface{}, at *trail) error {
+	for j, i := range ifi {
+		if !at.enter(i) {
+			continue
+		}
+		if err := at.push(Index(j)); err != nil {
+			return err
+		}
+		err := q.selectFromInterface(i, at)
+		at.pop()
+		at.leave(i)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		rs = append(rs, js...)
 	}
-	return rs, nil
+	return nil
 }
 
-func (q Query) selectFromMap(ifi map[string]interface{}) ([]Result, error) {
-	rs := make([]Result, 0, len(q.choices))
-	for _, key := range q.choices {
-		r, err := q.selectFromMapWithKey(key, nil, ifi)
-		if err != nil {
-			return nil, err
+func (q Query) selectFromMap(ifi map[string]interface{}, at *trail) error {
+	at.begin(Step{Kind: StepTable})
+	defer at.end()
+	for _, key := range q.Choices {
+		if err := q.selectFromMapWithKey(key, at, ifi); err != nil {
+			return err
 		}
-		rs = append(rs, r...)
 	}
-	return rs, nil
+	return nil
 }
 
-func (q Query) selectFromMapWithKey(key Accepter, where []string, ifi map[string]interface{}) ([]Result, error) {
-	label, value, err := key.Accept(ifi)
+func (q Query) selectFromMapWithKey(key Accepter, at *trail, ifi map[string]interface{}) error {
+	label, value, found, err := key.Accept(ifi)
+	if at.tracing() {
+		at.noteAccept(key, found, err)
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if q.depth == TokLevelAny && value == nil {
-		return q.traverseMap(key, where, ifi)
+	if q.Depth == TokLevelAny && !found {
+		if at.index != nil {
+			if ok, err := q.selectFromIndex(key, at, ifi); ok {
+				return err
+			}
+		}
+		return q.traverseMap(key, at, ifi)
 	}
-	if value = q.applySelector(value); value == nil {
-		return nil, nil
+	if !found {
+		return nil
 	}
-	rs, err := q.applyMatcher(value, append(where, label))
-	if err == nil {
-		rs, err = q.applyQuery(rs)
+	if err := at.push(Key(label)); err != nil {
+		return err
 	}
-	return rs, err
+	err = q.selectFromValue(value, at)
+	at.pop()
+	return err
 }
 
-func (q Query) applyQuery(rs []Result) ([]Result, error) {
-	if q.next == nil {
-		return rs, nil
+func (q Query) selectFromValue(ifi interface{}, at *trail) error {
+	switch q.Get.(type) {
+	case Keys, Values, Entries:
+		return q.selectEntries(ifi, at)
 	}
-	xs := make([]Result, 0, len(rs))
-	for _, r := range rs {
-		if isValue(r.Value) {
-			return nil, fmt.Errorf("query: can not apply query to value %v (%q)", r.Value, q.next)
+	offset, elem := q.selectorOffset(normalize(ifi))
+	ifi, ok := q.applySelector(ifi)
+	if q.Get != nil && at.tracing() {
+		at.note(Step{Kind: StepSelect, Node: q.Get.String(), Ok: ok})
+	}
+	if !ok {
+		return nil
+	}
+	switch q.Get.(type) {
+	case Last, At, Range:
+		return q.selectElements(ifi.([]interface{}), at, offset)
+	}
+	if !elem {
+		return q.applyMatcher(ifi, at)
+	}
+	if err := at.push(Index(offset)); err != nil {
+		return err
+	}
+	err := q.applyMatcher(ifi, at)
+	at.pop()
+	return err
+}
+
+// selectEntries gives the list of the keys, values or entries of a table.
+// With a predicate or a subquery, each element of the list is given on its own
+// at the path of its key and the predicate is matched against its entry.
+func (q Query) selectEntries(ifi interface{}, at *trail) error {
+	got, ok := q.applySelector(ifi)
+	if at.tracing() {
+		at.note(Step{Kind: StepSelect, Node: q.Get.String(), Ok: ok})
+	}
+	if !ok {
+		return nil
+	}
+	if q.Match == nil && q.Next == nil {
+		return q.applyQuery(got, at)
+	}
+	var (
+		tab = normalize(ifi).(map[string]interface{})
+		vs  = got.([]interface{})
+	)
+	for j, k := range sortedKeys(tab) {
+		if q.Match != nil {
+			ok, err := q.Match.Match(makeEntry(k, tab[k]))
+			if at.tracing() {
+				at.note(Step{Kind: StepMatch, Node: q.Match.String(), Ok: ok, Err: err}, Key(k))
+			}
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
 		}
-		rs, err := q.next.Select(r.Value)
-		if err != nil {
-			return nil, err
+		if err := at.push(Key(k)); err != nil {
+			return err
 		}
-		for i := range rs {
-			rs[i].Paths = append(r.Paths, rs[i].Paths...)
+		err := q.applyQuery(vs[j], at)
+		at.pop()
+		if err != nil {
+			return err
 		}
-		xs = append(xs, rs...)
 	}
-	return xs, nil
+	return nil
+}
+
+func (q Query) applyQuery(ifi interface{}, at *trail) error {
+	if q.Next == nil {
+		at.note(Step{Kind: StepResult, Value: ifi})
+		return at.send(makeResult(at.Path(), ifi))
+	}
+	if _, ok := q.Next.(Axis); ok {
+		return selectWith(q.Next, ifi, at)
+	}
+	view := normalize(ifi)
+	if isNull(view) {
+		return nil
+	}
+	if isValue(view) {
+		return fmt.Errorf("query: can not apply query to value %v (%q)", ifi, q.Next)
+	}
+	return selectWith(q.Next, ifi, at)
 }
 
-func (q Query) applySelector(ifi interface{}) interface{} {
-	if q.get != nil {
-		ifi = q.get.Select(ifi)
+func (q Query) applySelector(ifi interface{}) (interface{}, bool) {
+	if q.Get == nil {
+		return ifi, true
+	}
+	got, ok := q.Get.Select(normalize(ifi))
+	switch q.Get.(type) {
+	case First, Last, At, Range, IsNull, Keys, Values, Entries:
+		return got, ok
+	default:
+		return ifi, ok
 	}
-	return ifi
 }
 
-func (q Query) applyMatcher(ifi interface{}, paths []string) ([]Result, error) {
-	if q.match == nil {
-		return []Result{makeResult(paths, ifi)}, nil
+func (q Query) selectorOffset(ifi interface{}) (int, bool) {
+	switch g := q.Get.(type) {
+	case First:
+		return 0, true
+	case Last:
+		arr, _ := ifi.([]interface{})
+		return g.offset(arr), false
+	case At:
+		return g.Index, false
+	case Range:
+		return g.Start, false
+	default:
+		return 0, false
 	}
-	switch is := ifi.(type) {
+}
+
+func (q Query) applyMatcher(ifi interface{}, at *trail) error {
+	if q.Match == nil {
+		return q.applyQuery(ifi, at)
+	}
+	switch is := normalize(ifi).(type) {
 	case map[string]interface{}:
-		if ok, err := q.match.Match(is); !ok || err != nil {
-			return nil, err
+		ok, err := q.Match.Match(is)
+		if at.tracing() {
+			at.note(Step{Kind: StepMatch, Node: q.Match.String(), Ok: ok, Err: err})
+		}
+		if !ok || err != nil {
+			return err
 		}
-		return []Result{makeResult(paths, is)}, nil
+		return q.applyQuery(ifi, at)
 	case []interface{}:
-		rs := make([]Result, 0, len(is))
-		for _, i := range is {
-			i, ok := i.(map[string]interface{})
+		return q.selectElements(is, at, 0)
+	default:
+		return fmt.Errorf("query: can not apply predicate to %T", ifi)
+	}
+}
+
+// selectElements gives each element of is matching the predicate of q, if
+// any, at its index in the array it comes from: is starts at offset in this
+// array.
+func (q Query) selectElements(is []interface{}, at *trail, offset int) error {
+	for j, i := range is {
+		if q.Match != nil {
+			doc, ok := normalize(i).(map[string]interface{})
 			if !ok {
+				if at.tracing() {
+					at.note(Step{Kind: StepMatch, Node: q.Match.String(), Detail: "not a table"}, Index(offset+j))
+				}
 				continue
 			}
-			ok, err := q.match.Match(i)
+			ok, err := q.Match.Match(doc)
+			if at.tracing() {
+				at.note(Step{Kind: StepMatch, Node: q.Match.String(), Ok: ok, Err: err}, Index(offset+j))
+			}
 			if err != nil {
-				return nil, err
+				return err
 			}
-			if ok {
-				rs = append(rs, makeResult(paths, i))
+			if !ok {
+				continue
 			}
 		}
-		return rs, nil
+		if err := at.push(Index(offset + j)); err != nil {
+			return err
+		}
+		err := q.applyQuery(i, at)
+		at.pop()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q Query) selectWildcard(w Wildcard, ifi interface{}, at *trail) error {
+	var (
+		keys []Segment
+		vs   []interface{}
+	)
+	switch is := normalize(ifi).(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(is) {
+			keys, vs = append(keys, Key(k)), append(vs, is[k])
+		}
+	case []interface{}:
+		for j, i := range is {
+			keys, vs = append(keys, Index(j)), append(vs, i)
+		}
 	default:
-		return nil, fmt.Errorf("query: can not apply predicate to %T", ifi)
+		return fmt.Errorf("query: can not select from %T", ifi)
 	}
+	at.begin(Step{Kind: StepTable})
+	defer at.end()
+	for i, seg := range keys {
+		if acceptValue(w.Kind, vs[i]) != nil || !q.descends(vs[i]) {
+			continue
+		}
+		if at.tracing() {
+			at.note(Step{Kind: StepAccept, Node: w.String()}, seg)
+		}
+		if err := at.push(seg); err != nil {
+			return err
+		}
+		err := q.selectFromValue(vs[i], at)
+		at.pop()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (q Query) traverseMap(key Accepter, where []string, ifi map[string]interface{}) ([]Result, error) {
-	rs := make([]Result, 0, len(ifi))
-	for k, is := range ifi {
-		ws := append(where, k)
-		switch i := is.(type) {
-		case []interface{}:
-			vs, err := q.traverseArray(key, ws, i)
-			if err != nil {
-				return nil, err
+// descends reports whether the predicate and the subquery of q can be applied
+// to ifi. The values picked by a wildcard that they can not be applied to are
+// skipped.
+func (q Query) descends(ifi interface{}) bool {
+	switch normalize(ifi).(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	if _, ok := q.Next.(Axis); ok {
+		return q.Match == nil
+	}
+	return q.Match == nil && q.Next == nil
+}
+
+// globstar gives q with the predicate of its globstar and the one of the
+// segment following it made lenient: the tables without an option they look
+// for do not match.
+func (q Query) globstar() Query {
+	if q.Match != nil {
+		q.Match = lenient{q.Match}
+	}
+	if next, ok := q.Next.(Query); ok && next.Match != nil {
+		next.Match = lenient{next.Match}
+		q.Next = next
+	}
+	return q
+}
+
+// selectGlobstar gives ifi, when it is a table, and all the tables below it to
+// the selector, the predicate and the subquery of q. The tables already given
+// by the globstar identified by id, when globstars follow each other, are not
+// given again.
+func (q Query) selectGlobstar(g Globstar, id string, ifi interface{}, at *trail) error {
+	switch is := normalize(ifi).(type) {
+	case map[string]interface{}:
+		if !at.move(id, Path(at.segs)) {
+			return nil
+		}
+		if at.tracing() {
+			at.note(Step{Kind: StepAccept, Node: g.String()})
+		}
+		if err := q.selectFromValue(ifi, at); err != nil {
+			return err
+		}
+		for _, k := range sortedKeys(is) {
+			if err := q.descendGlobstar(g, id, Key(k), is[k], at); err != nil {
+				return err
 			}
-			rs = append(rs, vs...)
-		case map[string]interface{}:
-			vs, err := q.selectFromMapWithKey(key, ws, i)
-			if err != nil {
-				return nil, err
+		}
+	case []interface{}:
+		for j, i := range is {
+			if err := q.descendGlobstar(g, id, Index(j), i, at); err != nil {
+				return err
 			}
-			rs = append(rs, vs...)
+		}
+	default:
+		return fmt.Errorf("query: can not select from %T", ifi)
+	}
+	return nil
+}
+
+func (q Query) descendGlobstar(g Globstar, id string, seg Segment, ifi interface{}, at *trail) error {
+	switch normalize(ifi).(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return nil
+	}
+	if !at.enter(ifi) {
+		return nil
+	}
+	defer at.leave(ifi)
+	if err := at.push(seg); err != nil {
+		return err
+	}
+	err := q.selectGlobstar(g, id, ifi, at)
+	at.pop()
+	return err
+}
+
+// lenient is a predicate for which the tables without an option it looks for
+// do not match.
+type lenient struct {
+	Matcher
+}
+
+func (m lenient) Match(doc map[string]interface{}) (bool, error) {
+	ok, err := m.Matcher.Match(doc)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return ok, err
+}
+
+func (q Query) traverseMap(key Accepter, at *trail, ifi map[string]interface{}) error {
+	for _, k := range sortedKeys(ifi) {
+		if !at.enter(ifi[k]) {
+			continue
+		}
+		if err := at.push(Key(k)); err != nil {
+			return err
+		}
+		var err error
+		switch i := normalize(ifi[k]).(type) {
+		case []interface{}:
+			err = q.traverseArray(key, at, i)
+		case map[string]interface{}:
+			at.begin(Step{Kind: StepTable})
+			err = q.selectFromMapWithKey(key, at, i)
+			at.end()
 		default:
 		}
+		at.pop()
+		at.leave(ifi[k])
+		if err != nil {
+			return err
+		}
 	}
-	return rs, nil
+	return nil
 }
 
-func (q Query) traverseArray(key Accepter, where []string, is []interface{}) ([]Result, error) {
-	rs := make([]Result, 0, len(is))
-	for _, i := range is {
-		switch i := i.(type) {
+func (q Query) traverseArray(key Accepter, at *trail, is []interface{}) error {
+	for j, i := range is {
+		if !at.enter(i) {
+			continue
+		}
+		if err := at.push(Index(j)); err != nil {
+			return err
+		}
+		var err error
+		switch i := normalize(i).(type) {
 		case map[string]interface{}:
-			vs, err := q.selectFromMapWithKey(key, where, i)
-			if err != nil {
-				return nil, err
-			}
-			rs = append(rs, vs...)
+			at.begin(Step{Kind: StepTable})
+			err = q.selectFromMapWithKey(key, at, i)
+			at.end()
 		case []interface{}:
-			vs, err := q.traverseArray(key, where, i)
-			if err != nil {
-				return nil, err
-			}
-			rs = append(rs, vs...)
+			err = q.traverseArray(key, at, i)
+		}
+		at.pop()
+		at.leave(i)
+		if err != nil {
+			return err
 		}
 	}
-	return rs, nil
+	return nil
 }
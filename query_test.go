@@ -131,13 +131,13 @@ func TestSelect(t *testing.T) {
 		{
 			Input: "..addr",
 			Want: []interface{}{
-				"239.192.0.1:31001",
-				"224.0.0.1:31001",
-				"10.10.1.1:10015",
-				"10.10.1.15:10015",
 				"10.10.0.1:10001",
 				"10.10.0.2:10001",
 				"10.10.0.3:10001",
+				"10.10.1.15:10015",
+				"239.192.0.1:31001",
+				"224.0.0.1:31001",
+				"10.10.1.1:10015",
 			},
 		},
 		{
@@ -167,11 +167,11 @@ func TestSelect(t *testing.T) {
 		},
 		{
 			Input: "@groups:at(0)",
-			Want:  []interface{}{grp0},
+			Want:  grp0,
 		},
 		{
 			Input: "@groups:last",
-			Want:  []interface{}{grp1},
+			Want:  grp1,
 		},
 		{
 			Input: "@groups:range(5, 10)",
@@ -208,3 +208,67 @@ func TestSelect(t *testing.T) {
 		}
 	}
 }
+
+func TestSelectNull(t *testing.T) {
+	doc := map[string]interface{}{
+		"empty": Null{},
+		"unset": nil,
+		"sub": map[string]interface{}{
+			"empty": "deeper",
+		},
+		"items": []interface{}{
+			map[string]interface{}{"name": "foo", "value": Null{}},
+			map[string]interface{}{"name": "bar", "value": int64(1)},
+		},
+	}
+	data := []struct {
+		Input string
+		Want  []interface{}
+	}{
+		{
+			Input: ".empty",
+			Want:  []interface{}{Null{}},
+		},
+		{
+			Input: "..empty",
+			Want:  []interface{}{Null{}},
+		},
+		{
+			Input: ".(empty,unset):null",
+			Want:  []interface{}{Null{}, Null{}},
+		},
+		{
+			Input: ".missing",
+		},
+		{
+			Input: ".(empty,unset).sub",
+		},
+		{
+			Input: ".items[value == null].name",
+			Want:  []interface{}{"foo"},
+		},
+		{
+			Input: ".items[value != null].name",
+			Want:  []interface{}{"bar"},
+		},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("error parsing %s: %s", d.Input, err)
+			continue
+		}
+		rs, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: error fetching data: %s", d.Input, err)
+			continue
+		}
+		var got []interface{}
+		for _, r := range rs {
+			got = append(got, r.Value)
+		}
+		if !reflect.DeepEqual(d.Want, got) {
+			t.Errorf("%s: results mismatched! want %v, got %v", d.Input, d.Want, got)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Record is one of the documents read by a RecordReader. Line is the number
+// of the first line of the record in its input.
+type Record struct {
+	Line int
+	Doc  map[string]interface{}
+}
+
+// RecordError is given by a RecordReader when a record can not be decoded.
+// The following records can still be read.
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// RecordReader reads a stream made of many documents, each of them queried on
+// its own.
+type RecordReader struct {
+	reader *bufio.Reader
+	marker string
+	decode func(io.Reader) (map[string]interface{}, error)
+	line   int
+}
+
+// NewJSONLinesReader reads line delimited JSON (NDJSON): each non blank line
+// holds a JSON object. Numbers are decoded as int64 or float64 and null as
+// Null.
+func NewJSONLinesReader(r io.Reader) *RecordReader {
+	return &RecordReader{
+		reader: bufio.NewReader(r),
+		decode: decodeJSONRecord,
+	}
+}
+
+// NewRecordReader reads documents separated by lines holding only marker
+// (eg "---") and decodes each of them with decode.
+func NewRecordReader(r io.Reader, marker string, decode func(io.Reader) (map[string]interface{}, error)) *RecordReader {
+	return &RecordReader{
+		reader: bufio.NewReader(r),
+		marker: marker,
+		decode: decode,
+	}
+}
+
+// Read gives the next record of the stream or io.EOF when there is no more
+// record. The error is a *RecordError when the record is invalid.
+func (r *RecordReader) Read() (Record, error) {
+	var (
+		buf   bytes.Buffer
+		first int
+	)
+	for {
+		str, err := r.reader.ReadString('\n')
+		if str != "" {
+			r.line++
+			text := strings.TrimSpace(str)
+			switch {
+			case r.marker != "" && text == r.marker:
+				if first > 0 {
+					return r.record(first, &buf)
+				}
+			case text == "" && first == 0:
+			default:
+				if first == 0 {
+					first = r.line
+				}
+				buf.WriteString(str)
+				if r.marker == "" {
+					return r.record(first, &buf)
+				}
+			}
+		}
+		if err == io.EOF && first > 0 {
+			return r.record(first, &buf)
+		}
+		if err != nil {
+			return Record{}, err
+		}
+	}
+}
+
+func (r *RecordReader) record(line int, buf *bytes.Buffer) (Record, error) {
+	doc, err := r.decode(buf)
+	if err != nil {
+		return Record{Line: line}, &RecordError{Line: line, Err: err}
+	}
+	return Record{Line: line, Doc: doc}, nil
+}
+
+func decodeJSONRecord(r io.Reader) (map[string]interface{}, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	s := streamer{dec: dec}
+	tok, err := s.token()
+	if err != nil {
+		return nil, err
+	}
+	ifi, err := s.value(tok)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := ifi.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("json: object expected")
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("json: unexpected data after object")
+	}
+	return doc, nil
+}
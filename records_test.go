@@ -0,0 +1,103 @@
+package query
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONLinesReader(t *testing.T) {
+	const input = `{"user": "alice", "action": "login", "code": 200}
+
+{"user": "bob", "action": "login", "code": 403}
+{"user": "carol", "action":
+{"user": "dave", "action": "logout", "code": 200}
+[1, 2]
+{"user": "erin"} {"user": "frank"}
+`
+	var (
+		rr    = NewJSONLinesReader(strings.NewReader(input))
+		users []interface{}
+		lines []int
+		bad   []int
+	)
+	q, err := Parse(".user")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	for {
+		rec, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var re *RecordError
+			if !errors.As(err, &re) {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			bad = append(bad, re.Line)
+			continue
+		}
+		rs, err := q.Select(rec.Doc)
+		if err != nil {
+			t.Fatalf("line %d: fail to select: %s", rec.Line, err)
+		}
+		for _, r := range rs {
+			users = append(users, r.Value)
+			lines = append(lines, rec.Line)
+		}
+	}
+	if want := []interface{}{"alice", "bob", "dave"}; !reflect.DeepEqual(users, want) {
+		t.Errorf("users mismatched! want %v, got %v", want, users)
+	}
+	if want := []int{1, 3, 5}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines mismatched! want %v, got %v", want, lines)
+	}
+	if want := []int{4, 6, 7}; !reflect.DeepEqual(bad, want) {
+		t.Errorf("bad records mismatched! want %v, got %v", want, bad)
+	}
+}
+
+func TestRecordReader(t *testing.T) {
+	const input = `---
+{
+	"name": "first",
+	"port": 80
+}
+---
+
+---
+{
+	"name": "second",
+
+	"port": 443
+}
+---
+{"name": }
+---
+{"name": "last"}`
+
+	rr := NewRecordReader(strings.NewReader(input), "---", decodeJSONRecord)
+	want := []Record{
+		{Line: 2, Doc: map[string]interface{}{"name": "first", "port": int64(80)}},
+		{Line: 9, Doc: map[string]interface{}{"name": "second", "port": int64(443)}},
+		{Line: 15},
+		{Line: 17, Doc: map[string]interface{}{"name": "last"}},
+	}
+	var got []Record
+	for {
+		rec, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && rec.Line != 15 {
+			t.Errorf("line %d: unexpected error: %s", rec.Line, err)
+		}
+		got = append(got, rec)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("records mismatched! want %v, got %v", want, got)
+	}
+}
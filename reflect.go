@@ -0,0 +1,226 @@
+package query
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// normalize gives the view of a value that the evaluator understands. Values
+// already in that shape are returned as is. Go structs, typed maps, slices
+// and pointers are unwrapped one level at a time: the tables and arrays
+// returned still hold the original values of their fields and elements.
+func normalize(ifi interface{}) interface{} {
+	switch ifi.(type) {
+	case nil, map[string]interface{}, []interface{}, string, int64, float64, bool, time.Time, Null:
+		return ifi
+	}
+	return normalizeValue(reflect.ValueOf(ifi))
+}
+
+func normalizeValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return normalizeElem(v)
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface()
+		}
+		return normalizeStruct(v)
+	case reflect.Map:
+		return normalizeMap(v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		return normalizeArray(v)
+	case reflect.Array:
+		return normalizeArray(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		}
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	default:
+		return v.Interface()
+	}
+}
+
+// normalizeElem gives the value v points to. A chain of pointers leading back
+// to one of its pointers holds no value and is given as Null.
+func normalizeElem(v reflect.Value) interface{} {
+	var seen map[uintptr]struct{}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return Null{}
+		}
+		if v.Kind() == reflect.Ptr {
+			if _, ok := seen[v.Pointer()]; ok {
+				return Null{}
+			}
+			if k := v.Elem().Kind(); k == reflect.Ptr || k == reflect.Interface {
+				if seen == nil {
+					seen = make(map[uintptr]struct{})
+				}
+				seen[v.Pointer()] = struct{}{}
+			}
+		}
+		v = v.Elem()
+	}
+	return normalizeValue(v)
+}
+
+func normalizeArray(v reflect.Value) interface{} {
+	arr := make([]interface{}, v.Len())
+	for i := range arr {
+		arr[i] = v.Index(i).Interface()
+	}
+	return arr
+}
+
+func normalizeMap(v reflect.Value) interface{} {
+	var key func(reflect.Value) string
+	switch v.Type().Key().Kind() {
+	case reflect.String:
+		key = func(k reflect.Value) string { return k.String() }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		key = func(k reflect.Value) string { return strconv.FormatInt(k.Int(), 10) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		key = func(k reflect.Value) string { return strconv.FormatUint(k.Uint(), 10) }
+	default:
+		return v.Interface()
+	}
+	tab := make(map[string]interface{}, v.Len())
+	for it := v.MapRange(); it.Next(); {
+		tab[key(it.Key())] = it.Value().Interface()
+	}
+	return tab
+}
+
+func normalizeStruct(v reflect.Value) interface{} {
+	fs := structFields(v.Type())
+	tab := make(map[string]interface{}, len(fs))
+	for _, f := range fs {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok {
+			continue
+		}
+		tab[f.name] = fv.Interface()
+	}
+	return tab
+}
+
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+type field struct {
+	name  string
+	index []int
+}
+
+var fieldsCache sync.Map
+
+func structFields(t reflect.Type) []field {
+	if fs, ok := fieldsCache.Load(t); ok {
+		return fs.([]field)
+	}
+	var (
+		fs   []field
+		seen = make(map[string]struct{})
+	)
+	collectFields(t, nil, seen, map[reflect.Type]struct{}{t: {}}, &fs)
+	fieldsCache.Store(t, fs)
+	return fs
+}
+
+func collectFields(t reflect.Type, index []int, seen map[string]struct{}, types map[reflect.Type]struct{}, fs *[]field) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, tagged := fieldName(f)
+		if name == "-" || (f.PkgPath != "" && !isEmbeddedStruct(f)) {
+			continue
+		}
+		if f.Anonymous && !tagged {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				embedded = append(embedded, f)
+				continue
+			}
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		*fs = append(*fs, field{
+			name:  name,
+			index: appendIndex(index, f.Index),
+		})
+	}
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if _, ok := types[ft]; ok {
+			continue
+		}
+		types[ft] = struct{}{}
+		collectFields(ft, appendIndex(index, f.Index), seen, types, fs)
+		delete(types, ft)
+	}
+}
+
+// isEmbeddedStruct reports whether the exported fields of an embedded struct
+// can be read even when the struct type itself is unexported.
+func isEmbeddedStruct(f reflect.StructField) bool {
+	return f.Anonymous && f.Type.Kind() == reflect.Struct
+}
+
+func fieldName(f reflect.StructField) (string, bool) {
+	for _, key := range []string{"toml", "json"} {
+		tag, ok := f.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			tag = tag[:i]
+		}
+		if tag != "" {
+			return tag, true
+		}
+	}
+	return f.Name, false
+}
+
+func appendIndex(index, more []int) []int {
+	ix := make([]int, 0, len(index)+len(more))
+	ix = append(ix, index...)
+	return append(ix, more...)
+}
@@ -0,0 +1,240 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testCred struct {
+	User   string `toml:"user"`
+	Passwd string `json:"passwd,omitempty"`
+}
+
+type testMeta struct {
+	Owner   string    `toml:"owner"`
+	Created time.Time `toml:"created"`
+}
+
+type testClient struct {
+	Addr  string    `toml:"addr"`
+	TLS   bool      `toml:"tls"`
+	RPS   uint16    `toml:"rps"`
+	Cred  *testCred `toml:"cred"`
+	Tags  []string  `toml:"tags"`
+	Skip  string    `toml:"-"`
+	local string
+}
+
+type testConfig struct {
+	testMeta
+	Service string                `json:"service"`
+	Clients []testClient          `toml:"client"`
+	Servers map[string]testClient `toml:"servers"`
+	Ports   [2]int                `toml:"ports"`
+	Limits  map[int]float32       `toml:"limits"`
+	Backup  *testClient           `toml:"backup"`
+	Data    []byte                `toml:"data"`
+	Extra   interface{}           `toml:"extra"`
+}
+
+func TestSelectStruct(t *testing.T) {
+	cfg := &testConfig{
+		testMeta: testMeta{
+			Owner:   "midbel",
+			Created: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		},
+		Service: "query",
+		Clients: []testClient{
+			{Addr: "10.0.0.1", RPS: 10, Cred: &testCred{User: "root"}},
+			{Addr: "10.0.0.2", TLS: true, RPS: 100, Tags: []string{"prod", "eu"}},
+			{Addr: "10.0.0.3", TLS: true, RPS: 50, Cred: &testCred{User: "admin", Passwd: "secret"}, Skip: "skip", local: "local"},
+		},
+		Servers: map[string]testClient{
+			"prime":  {Addr: "10.1.0.1"},
+			"backup": {Addr: "10.1.0.2", TLS: true},
+		},
+		Ports:  [2]int{80, 443},
+		Limits: map[int]float32{1: 0.5},
+		Data:   []byte("raw"),
+		Extra:  map[string]interface{}{"addr": "10.2.0.1"},
+	}
+	data := []struct {
+		Input string
+		Want  []interface{}
+	}{
+		{
+			Input: ".service",
+			Want:  []interface{}{"query"},
+		},
+		{
+			Input: ".owner",
+			Want:  []interface{}{"midbel"},
+		},
+		{
+			Input: ".created",
+			Want:  []interface{}{time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)},
+		},
+		{
+			Input: ".client[tls == true && rps > 60].addr",
+			Want:  []interface{}{"10.0.0.2"},
+		},
+		{
+			Input: ".client:last.cred",
+			Want:  []interface{}{cfg.Clients[2].Cred},
+		},
+		{
+			Input: "..cred.user",
+			Want:  []interface{}{"root", "admin"},
+		},
+		{
+			Input: "..passwd",
+			Want:  []interface{}{"", "secret"},
+		},
+		{
+			Input: ".client:first.rps:int",
+			Want:  []interface{}{uint16(10)},
+		},
+		{
+			Input: ".client:at(1).tags:first",
+			Want:  []interface{}{"prod"},
+		},
+		{
+			Input: ".servers.backup.addr",
+			Want:  []interface{}{"10.1.0.2"},
+		},
+		{
+			Input: ".servers.backup[tls == true].addr",
+			Want:  []interface{}{"10.1.0.2"},
+		},
+		{
+			Input: "@ports:last",
+			Want:  []interface{}{443},
+		},
+		{
+			Input: ".limits.1",
+			Want:  []interface{}{float32(0.5)},
+		},
+		{
+			Input: ".backup:null",
+			Want:  []interface{}{Null{}},
+		},
+		{
+			Input: "%data:string",
+			Want:  []interface{}{[]byte("raw")},
+		},
+		{
+			Input: ".extra.addr",
+			Want:  []interface{}{"10.2.0.1"},
+		},
+		{
+			Input: "..(Skip,local)",
+		},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		rs, err := q.Select(cfg)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", d.Input, err)
+			continue
+		}
+		var got []interface{}
+		for _, r := range rs {
+			got = append(got, r.Value)
+			v, err := r.Paths.Resolve(cfg)
+			if err != nil {
+				t.Errorf("%s: fail to resolve %s: %s", d.Input, r.Paths, err)
+				continue
+			}
+			if isValue(r.Value) && !isNull(r.Value) && !reflect.DeepEqual(v, r.Value) {
+				t.Errorf("%s: %s resolved to %v, want %v", d.Input, r.Paths, v, r.Value)
+			}
+		}
+		if !reflect.DeepEqual(d.Want, got) {
+			t.Errorf("%s: results mismatched! want %v, got %v", d.Input, d.Want, got)
+		}
+	}
+}
+
+func TestSelectStructReference(t *testing.T) {
+	cfg := testConfig{
+		Clients: []testClient{
+			{Addr: "10.0.0.1", Cred: &testCred{User: "root"}},
+		},
+	}
+	q, err := Parse(".client.cred")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	rs, err := q.Select(cfg)
+	if err != nil {
+		t.Fatalf("fail to select: %s", err)
+	}
+	if len(rs) != 1 {
+		t.Fatalf("results mismatched! want 1, got %d", len(rs))
+	}
+	cred, ok := rs[0].Value.(*testCred)
+	if !ok || cred != cfg.Clients[0].Cred {
+		t.Fatalf("result does not reference the original value: %#v", rs[0].Value)
+	}
+}
+
+type testNode struct {
+	Name string               `toml:"name"`
+	Next *testNode            `toml:"next"`
+	Kids []*testNode          `toml:"kids"`
+	Refs map[string]*testNode `toml:"refs"`
+}
+
+func TestSelectCyclicStruct(t *testing.T) {
+	var (
+		root  = &testNode{Name: "root"}
+		child = &testNode{Name: "child", Next: root}
+	)
+	root.Next = root
+	root.Kids = []*testNode{child}
+	child.Refs = map[string]*testNode{"self": child, "root": root}
+
+	var ptr interface{}
+	ptr = &ptr
+
+	data := []struct {
+		Input string
+		Doc   interface{}
+		Paths []string
+	}{
+		{Input: "..missing", Doc: root},
+		{Input: "..name", Doc: root, Paths: []string{"name"}},
+		{Input: "..self", Doc: root, Paths: []string{"kids[0].refs.self"}},
+		{Input: ".kids..missing", Doc: root},
+		{Input: ".kids..name", Doc: root, Paths: []string{"kids[0].name"}},
+		{Input: ".**.name", Doc: root, Paths: []string{"name", "kids[0].name"}},
+		{Input: "..self,..missing", Doc: root, Paths: []string{"kids[0].refs.self"}},
+		{Input: "..missing", Doc: map[string]interface{}{"ptr": &ptr}},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		for _, doc := range []interface{}{d.Doc, NewKeyIndex(d.Doc)} {
+			rs, err := q.Select(doc)
+			if err != nil {
+				t.Errorf("%s: fail to select: %s", d.Input, err)
+				continue
+			}
+			var paths []string
+			for _, r := range rs {
+				paths = append(paths, r.Paths.String())
+			}
+			if !reflect.DeepEqual(d.Paths, paths) {
+				t.Errorf("%s: paths mismatched! want %v, got %v", d.Input, d.Paths, paths)
+			}
+		}
+	}
+}
@@ -92,10 +92,10 @@ func (s *Scanner) Scan() Token {
 }
 
 func (s *Scanner) scanExpr() rune {
+	s.skip(isSpace)
 	if s.isDone() {
-		return TokIllegal
+		return s.scanEnd()
 	}
-	s.skip(isBlank)
 	var (
 		pos = s.curr
 		tok rune
@@ -121,7 +121,7 @@ func (s *Scanner) scanExpr() rune {
 	default:
 		tok = TokIllegal
 	}
-	if tok == TokIllegal && s.curr > pos {
+	if tok == TokIllegal {
 		s.reset(pos)
 		s.scanIllegal(func(r rune) bool { return isControl(r) || isOperator(r) })
 	}
@@ -130,7 +130,7 @@ func (s *Scanner) scanExpr() rune {
 
 func (s *Scanner) scanDefault() rune {
 	if s.isDone() {
-		return TokEOF
+		return s.scanEnd()
 	}
 	var (
 		pos = s.curr
@@ -140,7 +140,9 @@ func (s *Scanner) scanDefault() rune {
 	case isDigit(s.char):
 		tok = s.scanDigit()
 	case isLetter(s.char):
-		tok = s.scanLiteral()
+		if tok = s.scanLiteral(); tok == TokNull {
+			tok = TokLiteral
+		}
 	case isQuote(s.char):
 		tok = s.scanQuote()
 	case isControl(s.char):
@@ -149,33 +151,44 @@ func (s *Scanner) scanDefault() rune {
 		tok = s.scanPattern()
 	case isSelector(s.char):
 		tok = s.scanSelector()
+	case s.char == star:
+		tok = s.scanWildcard()
 	default:
 		tok = TokIllegal
 	}
 	switch tok {
 	case TokComma:
-		s.skip(isBlank)
+		s.skip(isSpace)
 	case TokIllegal:
-		if s.curr > pos {
-			s.reset(pos)
-			s.scanIllegal(isControl)
-		}
+		s.reset(pos)
+		s.scanIllegal(isControl)
 	default:
 	}
 	return tok
 }
 
 func (s *Scanner) scanIllegal(isDelim func(r rune) bool) rune {
-	for !s.isDone() && !isDelim(s.char) {
+	for !s.isDone() {
 		s.writeRune(s.char)
 		s.readRune()
+		if isDelim(s.char) {
+			break
+		}
 	}
 	return TokIllegal
 }
 
+func (s *Scanner) scanEnd() rune {
+	if s.char == TokIllegal {
+		s.char = TokEOF
+		return TokIllegal
+	}
+	return TokEOF
+}
+
 func (s *Scanner) scanUntil(accept func(r rune) bool) bool {
 	isDelim := func(r rune) bool {
-		return isControl(r) || isOperator(r) || isBlank(r) || isSelector(r)
+		return isControl(r) || isOperator(r) || isSpace(r) || isSelector(r)
 	}
 	for !s.isDone() && !isDelim(s.char) {
 		if !accept(s.char) {
@@ -199,6 +212,15 @@ func (s *Scanner) scanSelector() rune {
 	return TokIllegal
 }
 
+func (s *Scanner) scanWildcard() rune {
+	s.readRune()
+	if s.char == star {
+		s.readRune()
+		return TokGlobstar
+	}
+	return TokWildcard
+}
+
 func (s *Scanner) scanBase() rune {
 	var accept func(r rune) bool
 	s.writeRune(s.char)
@@ -469,8 +491,6 @@ func (s *Scanner) scanQuote() rune {
 			switch char := s.scanEscape(); char {
 			case utf8.RuneError:
 				return TokIllegal
-			case 0:
-				continue
 			default:
 				s.char = char
 			}
@@ -486,11 +506,11 @@ func (s *Scanner) scanQuote() rune {
 }
 
 func (s *Scanner) scanEscape() rune {
+	s.readRune()
 	if s.char == unicode4 || s.char == unicode8 {
 		return s.scanUnicodeEscape()
 	}
 	if char, ok := escapes[s.char]; ok {
-		s.readRune()
 		return char
 	}
 	return utf8.RuneError
@@ -498,36 +518,35 @@ func (s *Scanner) scanEscape() rune {
 
 func (s *Scanner) scanUnicodeEscape() rune {
 	var (
-		char   int32
-		offset int32
-		step   int32
+		char rune
+		step = 4
 	)
-	if s.char == unicode4 {
-		step, offset = 4, 12
-	} else {
-		step, offset = 8, 28
+	if s.char == unicode8 {
+		step = 8
 	}
-	for i := int32(0); i < step; i++ {
+	for i := 0; i < step; i++ {
 		s.readRune()
 		var x rune
 		switch {
 		case s.char >= '0' && s.char <= '9':
 			x = s.char - '0'
 		case s.char >= 'a' && s.char <= 'f':
-			x = s.char - 'a'
+			x = s.char - 'a' + 10
 		case s.char >= 'A' && s.char <= 'F':
-			x = s.char - 'A'
+			x = s.char - 'A' + 10
 		default:
 			return utf8.RuneError
 		}
-		char |= x << offset
-		offset -= step
+		char = char<<4 | x
+	}
+	if !utf8.ValidRune(char) {
+		return utf8.RuneError
 	}
 	return char
 }
 
 func (s *Scanner) scanOperator() rune {
-	var k rune
+	k := TokIllegal
 	switch s.char {
 	case star:
 		if s.nextRune() == equal {
@@ -629,6 +648,14 @@ func (s *Scanner) scanControl() rune {
 			s.readRune()
 			k = TokLevelGreedy
 		}
+		if k == TokLevelAny && s.nextRune() == caret {
+			s.readRune()
+			k = TokAncestor
+		}
+	case caret:
+		k = TokParent
+	case tilde:
+		k = TokSibling
 	}
 	s.readRune()
 	return k
@@ -702,6 +729,10 @@ func isBlank(r rune) bool {
 	return r == space || r == tab
 }
 
+func isSpace(r rune) bool {
+	return isBlank(r) || r == newline || r == carriage
+}
+
 func isDigit(r rune) bool {
 	return r >= '0' && r <= '9'
 }
@@ -726,7 +757,8 @@ func isOperator(r rune) bool {
 
 func isControl(r rune) bool {
 	return r == percent || r == arobase || r == dollar || r == dot ||
-		r == lsquare || r == rsquare || r == lparen || r == rparen || r == comma
+		r == lsquare || r == rsquare || r == lparen || r == rparen || r == comma ||
+		r == caret || r == tilde
 }
 
 func isPattern(r rune) bool {
@@ -746,5 +778,5 @@ func isOctal(r rune) bool {
 }
 
 func isHexa(r rune) bool {
-	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'Z')
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
 }
@@ -127,6 +127,18 @@ func TestScanner(t *testing.T) {
 				createToken("", TokEndExpr),
 			},
 		},
+		{
+			Input: "null[bar != null]:null",
+			Tokens: []Token{
+				createToken("null", TokLiteral),
+				createToken("", TokBegExpr),
+				createToken("bar", TokLiteral),
+				createToken("", TokNotEqual),
+				createToken("null", TokNull),
+				createToken("", TokEndExpr),
+				createToken("null", TokSelectNull),
+			},
+		},
 		{
 			Input: "foo[bar $= \"value\"]",
 			Tokens: []Token{
@@ -306,6 +318,43 @@ func TestScanner(t *testing.T) {
 				createToken("", TokEndExpr),
 			},
 		},
+		{
+			Input: `"\u00e9\t\"\U0001F600"`,
+			Tokens: []Token{
+				createToken("\u00e9\t\"\U0001F600", TokLiteral),
+			},
+		},
+		{
+			Input: `"\uD800"`,
+			Tokens: []Token{
+				createToken(`"\uD800"`, TokIllegal),
+			},
+		},
+		{
+			Input: "foo:at(-1)",
+			Tokens: []Token{
+				createToken("foo", TokLiteral),
+				createToken("at", TokSelectAt),
+				createToken("", TokBegGrp),
+				createToken("-1", TokIllegal),
+				createToken("", TokEndGrp),
+			},
+		},
+		{
+			Input: "foo[bar ",
+			Tokens: []Token{
+				createToken("foo", TokLiteral),
+				createToken("", TokBegExpr),
+				createToken("bar", TokLiteral),
+			},
+		},
+		{
+			Input: "foo\xff",
+			Tokens: []Token{
+				createToken("foo", TokLiteral),
+				createToken("", TokIllegal),
+			},
+		},
 	}
 	for _, d := range data {
 		s := NewScanner(d.Input)
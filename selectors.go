@@ -1,121 +1,229 @@
 package query
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type Truthy struct{}
 
-func (_ Truthy) Select(ifi interface{}) interface{} {
+func (_ Truthy) Select(ifi interface{}) (interface{}, bool) {
 	if isTrue(ifi) {
-		return ifi
+		return ifi, true
 	}
-	return nil
+	return nil, false
 }
 
 type Falsy struct{}
 
-func (_ Falsy) Select(ifi interface{}) interface{} {
+func (_ Falsy) Select(ifi interface{}) (interface{}, bool) {
 	if !isTrue(ifi) {
-		return ifi
+		return ifi, true
 	}
-	return nil
+	return nil, false
 }
 
 type Int struct{}
 
-func (_ Int) Select(ifi interface{}) interface{} {
+func (_ Int) Select(ifi interface{}) (interface{}, bool) {
 	_, ok := ifi.(int64)
 	if !ok {
-		return nil
+		return nil, ok
 	}
-	return ifi
+	return ifi, ok
 }
 
 type Float struct{}
 
-func (_ Float) Select(ifi interface{}) interface{} {
+func (_ Float) Select(ifi interface{}) (interface{}, bool) {
 	_, ok := ifi.(float64)
 	if !ok {
-		return nil
+		return nil, ok
 	}
-	return ifi
+	return ifi, ok
 }
 
 type Number struct{}
 
-func (_ Number) Select(ifi interface{}) interface{} {
+func (_ Number) Select(ifi interface{}) (interface{}, bool) {
 	switch ifi.(type) {
 	case int64, float64:
-		return ifi
+		return ifi, true
 	default:
-		return nil
+		return nil, false
 	}
 }
 
 type Boolean struct{}
 
-func (_ Boolean) Select(ifi interface{}) interface{} {
+func (_ Boolean) Select(ifi interface{}) (interface{}, bool) {
 	_, ok := ifi.(bool)
 	if !ok {
-		return nil
+		return nil, ok
 	}
-	return ifi
+	return ifi, ok
 }
 
 type String struct{}
 
-func (_ String) Select(ifi interface{}) interface{} {
+func (_ String) Select(ifi interface{}) (interface{}, bool) {
 	_, ok := ifi.(string)
 	if !ok {
-		return nil
+		return nil, ok
+	}
+	return ifi, ok
+}
+
+type IsNull struct{}
+
+func (_ IsNull) Select(ifi interface{}) (interface{}, bool) {
+	if !isNull(ifi) {
+		return nil, false
 	}
-	return ifi
+	return Null{}, true
 }
 
 type First struct{}
 
-func (_ First) Select(ifi interface{}) interface{} {
+func (_ First) Select(ifi interface{}) (interface{}, bool) {
 	arr, ok := ifi.([]interface{})
 	if !ok || len(arr) == 0 {
-		return nil
+		return nil, false
 	}
-	return arr[0]
+	return arr[0], true
 }
 
 type Last struct{}
 
-func (_ Last) Select(ifi interface{}) interface{} {
+func (_ Last) Select(ifi interface{}) (interface{}, bool) {
 	arr, ok := ifi.([]interface{})
 	if !ok || len(arr) == 0 {
-		return nil
+		return nil, false
 	}
-	return arr[len(arr)-1:]
+	return arr[len(arr)-1:], true
+}
+
+func (_ Last) offset(arr []interface{}) int {
+	if len(arr) == 0 {
+		return 0
+	}
+	return len(arr) - 1
 }
 
 type At struct {
-	index int
+	Index int
 }
 
-func (a At) Select(ifi interface{}) interface{} {
+func (a At) Select(ifi interface{}) (interface{}, bool) {
 	arr, ok := ifi.([]interface{})
-	if !ok || len(arr) == 0 || a.index >= len(arr) {
-		return nil
+	if !ok || a.Index < 0 || a.Index >= len(arr) {
+		return nil, false
 	}
-	return arr[a.index : a.index+1]
+	return arr[a.Index : a.Index+1], true
 }
 
 type Range struct {
-	start int
-	end   int
+	Start int
+	End   int
 }
 
-func (r Range) Select(ifi interface{}) interface{} {
+func (r Range) Select(ifi interface{}) (interface{}, bool) {
 	arr, ok := ifi.([]interface{})
 	if !ok || len(arr) == 0 {
-		return nil
+		return nil, false
+	}
+	if r.End == 0 {
+		r.End = len(arr)
+	}
+	if r.Start >= 0 && r.Start < r.End && r.End <= len(arr) {
+		return arr[r.Start:r.End], true
+	}
+	return nil, false
+}
+
+// Keys gives the keys of a table in order.
+type Keys struct{}
+
+func (_ Keys) Select(ifi interface{}) (interface{}, bool) {
+	tab, ok := ifi.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	ks := sortedKeys(tab)
+	vs := make([]interface{}, len(ks))
+	for i, k := range ks {
+		vs[i] = k
+	}
+	return vs, true
+}
+
+// Values gives the values of a table in the order of their keys.
+type Values struct{}
+
+func (_ Values) Select(ifi interface{}) (interface{}, bool) {
+	tab, ok := ifi.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	ks := sortedKeys(tab)
+	vs := make([]interface{}, len(ks))
+	for i, k := range ks {
+		vs[i] = tab[k]
 	}
-	if r.end == 0 {
-		r.end = len(arr)
+	return vs, true
+}
+
+// Entries gives the options of a table in the order of their keys as tables
+// holding the key and the value of each option.
+type Entries struct{}
+
+func (_ Entries) Select(ifi interface{}) (interface{}, bool) {
+	tab, ok := ifi.(map[string]interface{})
+	if !ok {
+		return nil, false
 	}
-	if r.start < r.end && r.end <= len(arr) {
-		return arr[r.start:r.end]
+	ks := sortedKeys(tab)
+	vs := make([]interface{}, len(ks))
+	for i, k := range ks {
+		vs[i] = makeEntry(k, tab[k])
+	}
+	return vs, true
+}
+
+func makeEntry(key string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"key":   key,
+		"value": value,
+	}
+}
+
+func (_ Truthy) String() string  { return ":truthy" }
+func (_ Falsy) String() string   { return ":falsy" }
+func (_ Int) String() string     { return ":int" }
+func (_ Float) String() string   { return ":float" }
+func (_ Number) String() string  { return ":number" }
+func (_ Boolean) String() string { return ":bool" }
+func (_ String) String() string  { return ":string" }
+func (_ IsNull) String() string  { return ":null" }
+func (_ First) String() string   { return ":first" }
+func (_ Last) String() string    { return ":last" }
+func (_ Keys) String() string    { return ":keys" }
+func (_ Values) String() string  { return ":values" }
+func (_ Entries) String() string { return ":entries" }
+
+func (a At) String() string {
+	return fmt.Sprintf(":at(%d)", a.Index)
+}
+
+func (r Range) String() string {
+	var b strings.Builder
+	b.WriteString(":range(")
+	b.WriteString(strconv.Itoa(r.Start))
+	b.WriteRune(comma)
+	if r.End != 0 {
+		b.WriteString(strconv.Itoa(r.End))
 	}
-	return nil
+	b.WriteRune(rparen)
+	return b.String()
 }
@@ -21,6 +21,16 @@ func TestSelector(t *testing.T) {
 			Want:     nil,
 			Selector: First{},
 		},
+		{
+			Data:     []interface{}{1, 2, 3},
+			Want:     nil,
+			Selector: At{Index: -1},
+		},
+		{
+			Data:     []interface{}{1, 2, 3},
+			Want:     nil,
+			Selector: Range{Start: -1, End: 2},
+		},
 		{
 			Data:     "string",
 			Want:     "string",
@@ -56,11 +66,129 @@ func TestSelector(t *testing.T) {
 			Want:     0.14,
 			Selector: Float{},
 		},
+		{
+			Data:     Null{},
+			Want:     Null{},
+			Selector: IsNull{},
+		},
+		{
+			Data:     nil,
+			Want:     Null{},
+			Selector: IsNull{},
+		},
+		{
+			Data:     "",
+			Want:     nil,
+			Selector: IsNull{},
+		},
+		{
+			Data:     Null{},
+			Want:     Null{},
+			Selector: Falsy{},
+		},
+		{
+			Data:     map[string]interface{}{"b": int64(2), "a": int64(1)},
+			Want:     []interface{}{"a", "b"},
+			Selector: Keys{},
+		},
+		{
+			Data:     map[string]interface{}{"b": int64(2), "a": int64(1)},
+			Want:     []interface{}{int64(1), int64(2)},
+			Selector: Values{},
+		},
+		{
+			Data: map[string]interface{}{"a": int64(1)},
+			Want: []interface{}{
+				map[string]interface{}{"key": "a", "value": int64(1)},
+			},
+			Selector: Entries{},
+		},
+		{
+			Data:     []interface{}{1, 2, 3},
+			Want:     nil,
+			Selector: Keys{},
+		},
 	}
 	for _, d := range data {
-		got := d.Select(d.Data)
+		got, ok := d.Select(d.Data)
+		if ok != (d.Want != nil) {
+			t.Errorf("found mismatched! want %t, got %t", d.Want != nil, ok)
+		}
 		if !reflect.DeepEqual(d.Want, got) {
 			t.Errorf("data mismatched! want %v, got %v", d.Want, got)
 		}
 	}
 }
+
+func TestSelectEntries(t *testing.T) {
+	data := []struct {
+		Input string
+		Paths []string
+		Want  []interface{}
+	}{
+		{
+			Input: ".servers:keys",
+			Paths: []string{"servers"},
+			Want:  []interface{}{[]interface{}{"backup", "groups", "prime"}},
+		},
+		{
+			Input: ".admin:values",
+			Paths: []string{"admin"},
+			Want:  []interface{}{[]interface{}{admin["dob"], admin["email"], admin["name"]}},
+		},
+		{
+			Input: ".servers:keys[key ~= /*up*/]",
+			Paths: []string{"servers.backup", "servers.groups"},
+			Want:  []interface{}{"backup", "groups"},
+		},
+		{
+			Input: ".servers:entries[key == \"prime\"]",
+			Paths: []string{"servers.prime"},
+			Want: []interface{}{
+				map[string]interface{}{"key": "prime", "value": prime},
+			},
+		},
+		{
+			Input: ".servers:values.qn",
+			Paths: []string{"servers.backup.qn", "servers.prime.qn"},
+			Want:  []interface{}{"backup.foobar.org", "prime.foobar.org"},
+		},
+		{
+			Input: ".admin:keys[key ^= \"e\" || key ^= \"n\"]",
+			Paths: []string{"admin.email", "admin.name"},
+			Want:  []interface{}{"email", "name"},
+		},
+		{
+			Input: ".client:keys",
+		},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		if str := q.(Node).String(); str != d.Input {
+			t.Errorf("%s: string mismatched! got %s", d.Input, str)
+		}
+		rs, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", d.Input, err)
+			continue
+		}
+		var (
+			paths []string
+			vs    []interface{}
+		)
+		for _, r := range rs {
+			paths = append(paths, r.Paths.String())
+			vs = append(vs, r.Value)
+		}
+		if !reflect.DeepEqual(d.Paths, paths) {
+			t.Errorf("%s: paths mismatched! want %v, got %v", d.Input, d.Paths, paths)
+		}
+		if !reflect.DeepEqual(d.Want, vs) {
+			t.Errorf("%s: results mismatched! want %v, got %v", d.Input, d.Want, vs)
+		}
+	}
+}
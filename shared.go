@@ -0,0 +1,227 @@
+package query
+
+import (
+	"fmt"
+)
+
+// walker looks for one of the keys of one of the queries of a Queryset while
+// the Queryset is evaluated in a single walk of the document.
+type walker struct {
+	Query
+	key   Accepter
+	index int
+	rs    []Result
+	err   error
+	held  bool
+	yield func(Result) bool
+}
+
+// walkers gives a walker for each key of each query of the Queryset or nil if
+// the Queryset can not be evaluated in a single walk.
+func (qs Queryset) walkers() []*walker {
+	if len(qs) < 2 {
+		return nil
+	}
+	var ws []*walker
+	for i := range qs {
+		q, ok := qs[i].(Query)
+		if !ok {
+			return nil
+		}
+		if _, ok := q.wildcard(); ok || hasAxis(q) {
+			return nil
+		}
+		for _, k := range q.Choices {
+			ws = append(ws, &walker{
+				Query: q,
+				key:   k,
+				index: i,
+			})
+		}
+	}
+	return ws
+}
+
+// selectShared evaluates all the walkers in a single walk of ifi. The results
+// of the first key of the first query are given as soon as they are found.
+// The results of the other keys are kept until all the keys have been looked
+// for in a table of the top level (the results of a query come in the order
+// of its keys for each of these tables) and the results of the other queries
+// until the end of the walk, so the order of the results is the same as if
+// the queries were evaluated one after the other. The errors of the walkers
+// whose results are kept are held back with them: they stop their walker and
+// are only returned once the results given before them have been.
+func selectShared(ws []*walker, n int, ifi interface{}, at *trail) error {
+	out := at.yield
+	defer func() {
+		at.yield = out
+	}()
+	for i, w := range ws {
+		if i == 0 {
+			w.yield = out
+			continue
+		}
+		w := w
+		w.held = true
+		w.yield = func(r Result) bool {
+			w.rs = append(w.rs, r)
+			return true
+		}
+	}
+	var (
+		pending = make([][]Result, n)
+		errs    = make([]error, n)
+	)
+	flush := func() error {
+		for _, w := range ws[1:] {
+			if w.index == 0 {
+				if err := yieldAll(out, w.rs); err != nil {
+					return err
+				}
+				if w.err != nil {
+					return w.err
+				}
+			} else if errs[w.index] == nil {
+				pending[w.index] = append(pending[w.index], w.rs...)
+				errs[w.index] = w.err
+			}
+			w.rs = nil
+		}
+		return nil
+	}
+	if err := sharedFromInterface(ws, ifi, at, flush); err != nil {
+		return err
+	}
+	for i, rs := range pending {
+		if err := yieldAll(out, rs); err != nil {
+			return err
+		}
+		if errs[i] != nil {
+			return errs[i]
+		}
+	}
+	return nil
+}
+
+func yieldAll(yield func(Result) bool, rs []Result) error {
+	for _, r := range rs {
+		if !yield(r) {
+			return errStop
+		}
+	}
+	return nil
+}
+
+func sharedFromInterface(ws []*walker, ifi interface{}, at *trail, flush func() error) error {
+	switch is := normalize(ifi).(type) {
+	case []interface{}:
+		for j, i := range is {
+			if !at.enter(i) {
+				continue
+			}
+			if err := at.push(Index(j)); err != nil {
+				return err
+			}
+			err := sharedFromInterface(ws, i, at, flush)
+			at.pop()
+			at.leave(i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		if err := sharedFromMap(ws, is, at); err != nil {
+			return err
+		}
+		return flush()
+	default:
+		return fmt.Errorf("query: can not select from %T", ifi)
+	}
+}
+
+func sharedFromMap(ws []*walker, ifi map[string]interface{}, at *trail) error {
+	var next []*walker
+	for _, w := range ws {
+		if w.err != nil {
+			continue
+		}
+		label, value, found, err := w.key.Accept(ifi)
+		if err != nil {
+			if w.held {
+				w.err = err
+				continue
+			}
+			return err
+		}
+		if !found {
+			if w.Depth == TokLevelAny {
+				next = append(next, w)
+			}
+			continue
+		}
+		if err := at.push(Key(label)); err != nil {
+			return err
+		}
+		at.yield = w.yield
+		err = w.selectFromValue(value, at)
+		at.pop()
+		if err != nil && w.held {
+			w.err = err
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(next) == 0 {
+		return nil
+	}
+	return sharedTraverseMap(next, ifi, at)
+}
+
+func sharedTraverseMap(ws []*walker, ifi map[string]interface{}, at *trail) error {
+	for _, k := range sortedKeys(ifi) {
+		if !at.enter(ifi[k]) {
+			continue
+		}
+		if err := at.push(Key(k)); err != nil {
+			return err
+		}
+		var err error
+		switch i := normalize(ifi[k]).(type) {
+		case []interface{}:
+			err = sharedTraverseArray(ws, i, at)
+		case map[string]interface{}:
+			err = sharedFromMap(ws, i, at)
+		}
+		at.pop()
+		at.leave(ifi[k])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sharedTraverseArray(ws []*walker, is []interface{}, at *trail) error {
+	for j, i := range is {
+		if !at.enter(i) {
+			continue
+		}
+		if err := at.push(Index(j)); err != nil {
+			return err
+		}
+		var err error
+		switch i := normalize(i).(type) {
+		case map[string]interface{}:
+			err = sharedFromMap(ws, i, at)
+		case []interface{}:
+			err = sharedTraverseArray(ws, i, at)
+		}
+		at.pop()
+		at.leave(i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
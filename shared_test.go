@@ -0,0 +1,139 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSelectShared(t *testing.T) {
+	arr := []interface{}{doc, grp0, []interface{}{client1, client2}}
+	data := []struct {
+		Input string
+		Doc   interface{}
+	}{
+		{Input: "..addr,..user,service", Doc: doc},
+		{Input: "..(addr,user),..@groups:last,..passwd", Doc: doc},
+		{Input: "..(addr,user),..every,.mode", Doc: arr},
+		{Input: "client[tls == true]..(user,passwd),..$cred.user", Doc: doc},
+		{Input: "..(/a*/,mode),servers.(prime,backup).addr", Doc: doc},
+		{Input: "..missing,..addr", Doc: tree},
+		{Input: "..node-1.leaf,..node-2..leaf,..root", Doc: tree},
+		{Input: "..addr,.**.user,.servers.$*.qn", Doc: doc},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		qs, ok := q.(Queryset)
+		if !ok {
+			t.Errorf("%s: queryset expected, got %T", d.Input, q)
+			continue
+		}
+		var want []Result
+		for _, q := range qs {
+			rs, err := q.Select(d.Doc)
+			if err != nil {
+				t.Errorf("%s: fail to select %s: %s", d.Input, q, err)
+				continue
+			}
+			want = append(want, rs...)
+		}
+		got, err := q.Select(d.Doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", d.Input, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: results mismatched!\nwant %v\ngot  %v", d.Input, want, got)
+		}
+		for i := range want {
+			var part []Result
+			Each(q, d.Doc, func(r Result) bool {
+				part = append(part, r)
+				return len(part) <= i
+			})
+			if !reflect.DeepEqual(want[:i+1], part) {
+				t.Errorf("%s: %d first results mismatched! want %v, got %v", d.Input, i+1, want[:i+1], part)
+				break
+			}
+		}
+	}
+}
+
+func TestSelectSharedError(t *testing.T) {
+	data := []string{
+		"..addr,.client[unknown == 1]",
+		"..addr,..cred[unknown == 1],..passwd",
+		"..(backup,cred)[qn ^= \"b\"],..passwd",
+		"..user,..(groups,client)[unknown == 1].every",
+	}
+	for _, str := range data {
+		q, err := Parse(str)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", str, err)
+			continue
+		}
+		var want []Result
+		for _, q := range q.(Queryset) {
+			err = Each(q, doc, func(r Result) bool {
+				want = append(want, r)
+				return true
+			})
+			if err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected %s, got %v", str, ErrNotFound, err)
+			continue
+		}
+		var got []Result
+		err = Each(q, doc, func(r Result) bool {
+			got = append(got, r)
+			return true
+		})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected %s, got %v", str, ErrNotFound, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: results mismatched!\nwant %v\ngot  %v", str, want, got)
+		}
+		if len(want) == 0 {
+			continue
+		}
+		got = got[:0]
+		err = Each(q, doc, func(r Result) bool {
+			got = append(got, r)
+			return false
+		})
+		if err != nil || len(got) != 1 {
+			t.Errorf("%s: iteration should stop before the error: %d result(s), %v", str, len(got), err)
+		}
+	}
+}
+
+var wideQueryset = "..leaf,..node-0,..node-1,..node-2,..missing,..(root,node-3),..none,..node-4"
+
+func BenchmarkQuerysetShared(b *testing.B) {
+	benchmarkSelect(b, wideQueryset, makeTree(4, 6))
+}
+
+func BenchmarkQuerysetSequential(b *testing.B) {
+	q, err := Parse(wideQueryset)
+	if err != nil {
+		b.Fatalf("fail to parse: %s", err)
+	}
+	doc := makeTree(4, 6)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, q := range q.(Queryset) {
+			if _, err := q.Select(doc); err != nil {
+				b.Fatalf("fail to select: %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrDuplicateKey is returned by StreamJSON when a key it looks for is given
+// twice in the same table.
+var ErrDuplicateKey = errors.New("key given twice in table")
+
+// StreamJSON evaluates q against the JSON document read from r and gives its
+// results to fn like Each does, without decoding the whole document first.
+//
+// The keys looked for in the current table only (eg .foo.bar) are evaluated
+// over the tokens of the document and only the values they select are
+// decoded. :first and :at pick their element while the array is read and the
+// predicates on an array of tables are evaluated one table at a time. The
+// queries needing other parts of the document (keys at any level, patterns,
+// querysets, axes,...) are evaluated as usual once the value they apply to,
+// or the whole document, is decoded. The results are the same as the ones of Select
+// on the decoded document and are given as soon as they are found. A key looked
+// for that is given twice in a table is reported with ErrDuplicateKey once the
+// results of its first value are given.
+func StreamJSON(q Queryer, r io.Reader, fn func(Result) bool) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	s := streamer{dec: dec}
+	tok, err := s.token()
+	if err != nil {
+		return err
+	}
+	at := trail{yield: fn}
+	if !streamable(q) || hasAxis(q) {
+		doc, err := s.value(tok)
+		if err != nil {
+			return err
+		}
+		at.root = doc
+		return stopped(selectWith(q, doc, &at))
+	}
+	return stopped(q.(Query).streamFrom(&s, tok, &at))
+}
+
+// streamable reports whether q can be evaluated over the tokens of a
+// document: it looks for a single key in the current table.
+func streamable(q Queryer) bool {
+	x, ok := q.(Query)
+	if !ok || x.Depth != TokLevelOne || len(x.Choices) != 1 {
+		return false
+	}
+	_, ok = x.Choices[0].(Name)
+	return ok
+}
+
+// streamFrom is the streaming counterpart of selectFromInterface for the value
+// starting with tok.
+func (q Query) streamFrom(s *streamer, tok json.Token, at *trail) error {
+	switch tok {
+	case json.Delim('{'):
+		return q.streamMap(s, at)
+	case json.Delim('['):
+		return s.elements(func(j int, tok json.Token) error {
+			if err := at.push(Index(j)); err != nil {
+				return err
+			}
+			err := q.streamFrom(s, tok, at)
+			at.pop()
+			return err
+		})
+	default:
+		ifi, err := s.value(tok)
+		if err != nil {
+			return err
+		}
+		return q.selectFromInterface(ifi, at)
+	}
+}
+
+func (q Query) streamMap(s *streamer, at *trail) error {
+	var (
+		key  = q.Choices[0].(Name)
+		done bool
+	)
+	for {
+		tok, err := s.token()
+		if err != nil {
+			return err
+		}
+		if tok == json.Delim('}') {
+			return nil
+		}
+		label, _ := tok.(string)
+		if tok, err = s.token(); err != nil {
+			return err
+		}
+		if label != key.Label {
+			if err := s.skip(tok); err != nil {
+				return err
+			}
+			continue
+		}
+		// the results of the first value are already given when the key is
+		// found again: it can not be replaced by its last value like
+		// encoding/json does.
+		if done {
+			return fmt.Errorf("%s: %w", label, ErrDuplicateKey)
+		}
+		done = true
+		if err := at.push(Key(label)); err != nil {
+			return err
+		}
+		err = q.streamValue(key, s, tok, at)
+		at.pop()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// streamValue is the streaming counterpart of selectFromValue. The value is
+// decoded when the query can not go on over its tokens.
+func (q Query) streamValue(key Name, s *streamer, tok json.Token, at *trail) error {
+	var ifi interface{}
+	switch tok {
+	case json.Delim('{'):
+		ifi = map[string]interface{}{}
+	case json.Delim('['):
+		ifi = []interface{}{}
+	default:
+		v, err := s.value(tok)
+		if err != nil {
+			return err
+		}
+		if err := acceptValue(key.Kind, v); err != nil {
+			return fmt.Errorf("%s: %w", key.Label, err)
+		}
+		return q.selectFromValue(v, at)
+	}
+	if err := acceptValue(key.Kind, ifi); err != nil {
+		return fmt.Errorf("%s: %w", key.Label, err)
+	}
+	if tok == json.Delim('[') {
+		switch q.Get.(type) {
+		case First, At:
+			return q.streamSelect(s, at)
+		case nil:
+			if q.Match != nil {
+				return s.elements(func(j int, tok json.Token) error {
+					ifi, err := s.value(tok)
+					if err != nil {
+						return err
+					}
+					return q.selectElements([]interface{}{ifi}, at, j)
+				})
+			}
+		}
+	}
+	if q.Get == nil && q.Match == nil && streamable(q.Next) {
+		return q.Next.(Query).streamFrom(s, tok, at)
+	}
+	ifi, err := s.value(tok)
+	if err != nil {
+		return err
+	}
+	return q.selectFromValue(ifi, at)
+}
+
+// streamSelect decodes only the element of the array picked by :first or :at.
+func (q Query) streamSelect(s *streamer, at *trail) error {
+	a, isAt := q.Get.(At)
+	return s.elements(func(j int, tok json.Token) error {
+		if j != a.Index {
+			return s.skip(tok)
+		}
+		ifi, err := s.value(tok)
+		if err != nil {
+			return err
+		}
+		if isAt {
+			return q.selectElements([]interface{}{ifi}, at, j)
+		}
+		if err := at.push(Index(0)); err != nil {
+			return err
+		}
+		err = q.applyMatcher(ifi, at)
+		at.pop()
+		return err
+	})
+}
+
+type streamer struct {
+	dec *json.Decoder
+}
+
+func (s *streamer) token() (json.Token, error) {
+	tok, err := s.dec.Token()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+// elements calls fn with the first token of each element of the array being
+// read until its end.
+func (s *streamer) elements(fn func(int, json.Token) error) error {
+	for j := 0; ; j++ {
+		tok, err := s.token()
+		if err != nil {
+			return err
+		}
+		if tok == json.Delim(']') {
+			return nil
+		}
+		if err := fn(j, tok); err != nil {
+			return err
+		}
+	}
+}
+
+// skip reads the value starting with tok without decoding it.
+func (s *streamer) skip(tok json.Token) error {
+	var depth int
+	for {
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if tok, err = s.token(); err != nil {
+			return err
+		}
+	}
+}
+
+// value decodes the value starting with tok. Numbers are given as int64 or
+// float64 and null as Null.
+func (s *streamer) value(tok json.Token) (interface{}, error) {
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			return s.table()
+		case '[':
+			return s.array()
+		default:
+			return nil, fmt.Errorf("json: unexpected %s", tok)
+		}
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return i, nil
+		}
+		return tok.Float64()
+	case nil:
+		return Null{}, nil
+	default:
+		return tok, nil
+	}
+}
+
+func (s *streamer) table() (map[string]interface{}, error) {
+	tab := make(map[string]interface{})
+	for {
+		tok, err := s.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok == json.Delim('}') {
+			return tab, nil
+		}
+		key, _ := tok.(string)
+		if tok, err = s.token(); err != nil {
+			return nil, err
+		}
+		if tab[key], err = s.value(tok); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *streamer) array() ([]interface{}, error) {
+	arr := []interface{}{}
+	err := s.elements(func(_ int, tok json.Token) error {
+		ifi, err := s.value(tok)
+		if err == nil {
+			arr = append(arr, ifi)
+		}
+		return err
+	})
+	return arr, err
+}
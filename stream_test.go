@@ -0,0 +1,175 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const streamDoc = `{
+	"name": "stream",
+	"version": 2,
+	"owner": null,
+	"servers": {
+		"alpha": {"ip": "10.0.0.1", "dc": "eqdc10"},
+		"list": [
+			{"name": "alpha", "enabled": true, "ports": [8000, 8001]},
+			{"name": "beta", "enabled": false, "ports": [9000]},
+			{"name": "gamma", "enabled": true, "ports": []}
+		]
+	},
+	"matrix": [[{"name": "m0"}], [{"name": "m1"}, {"name": "m2"}]],
+	"ratio": 0.5
+}`
+
+func TestStreamJSON(t *testing.T) {
+	queries := []string{
+		".name",
+		".owner",
+		".ratio",
+		".servers.alpha.ip",
+		".servers.list.name",
+		".servers.@list:first.name",
+		".servers.list:at(1).name",
+		".servers.list:at(1)",
+		".servers.list:at(5).name",
+		".servers.list[enabled == true].name",
+		".servers.list[enabled == true].ports:first",
+		".servers.list:last.name",
+		".matrix.name",
+		".servers..name",
+		"..ip",
+		".name,.version",
+		".missing.name",
+		".servers.alpha.ip^.dc",
+		".servers.*.name",
+		".**.ports:first",
+		".servers.alpha:keys",
+		".servers:entries[key == \"alpha\"].value.ip",
+	}
+	s := streamer{dec: json.NewDecoder(strings.NewReader(streamDoc))}
+	s.dec.UseNumber()
+	tok, _ := s.token()
+	doc, err := s.value(tok)
+	if err != nil {
+		t.Fatalf("fail to decode document: %s", err)
+	}
+	for _, str := range queries {
+		q, err := Parse(str)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", str, err)
+			continue
+		}
+		want, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", str, err)
+			continue
+		}
+		var got []Result
+		err = StreamJSON(q, strings.NewReader(streamDoc), func(r Result) bool {
+			got = append(got, r)
+			return true
+		})
+		if err != nil {
+			t.Errorf("%s: fail to stream: %s", str, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: results mismatched! want %v, got %v", str, want, got)
+		}
+	}
+}
+
+func TestStreamJSONStop(t *testing.T) {
+	q, err := Parse(".servers.list.name")
+	if err != nil {
+		t.Fatalf("fail to parse: %s", err)
+	}
+	var got []Result
+	err = StreamJSON(q, strings.NewReader(streamDoc), func(r Result) bool {
+		got = append(got, r)
+		return false
+	})
+	if err != nil {
+		t.Fatalf("fail to stream: %s", err)
+	}
+	if len(got) != 1 || got[0].Value != "alpha" {
+		t.Errorf("unexpected results: %v", got)
+	}
+}
+
+func TestStreamJSONInvalid(t *testing.T) {
+	data := []struct {
+		Query string
+		Input string
+	}{
+		{Query: ".name.first", Input: streamDoc},
+		{Query: ".$servers.@alpha", Input: streamDoc},
+		{Query: ".name", Input: `{"name": "stream"`},
+		{Query: ".servers:first", Input: `{"servers": [1, }`},
+		{Query: "..name", Input: `{"name": `},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Query)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Query, err)
+			continue
+		}
+		err = StreamJSON(q, strings.NewReader(d.Input), func(Result) bool {
+			return true
+		})
+		if err == nil {
+			t.Errorf("%s: expected error but query streamed", d.Query)
+		}
+	}
+}
+
+func TestStreamJSONDuplicateKeys(t *testing.T) {
+	const input = `{
+		"name": "first",
+		"servers": {"alpha": {"ip": "10.0.0.1"}, "alpha": {"ip": "10.0.0.2"}},
+		"name": "last"
+	}`
+	data := []struct {
+		Input string
+		Want  []interface{}
+		Dup   bool
+	}{
+		{Input: ".name", Want: []interface{}{"first"}, Dup: true},
+		{Input: ".servers.alpha.ip", Want: []interface{}{"10.0.0.1"}, Dup: true},
+		{Input: ".servers", Want: []interface{}{map[string]interface{}{"alpha": map[string]interface{}{"ip": "10.0.0.2"}}}},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		var got []interface{}
+		err = StreamJSON(q, strings.NewReader(input), func(r Result) bool {
+			got = append(got, r.Value)
+			return true
+		})
+		if d.Dup != errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("%s: unexpected error: %v", d.Input, err)
+			continue
+		}
+		if !d.Dup && err != nil {
+			t.Errorf("%s: fail to stream: %s", d.Input, err)
+			continue
+		}
+		if !reflect.DeepEqual(d.Want, got) {
+			t.Errorf("%s: results mismatched! want %v, got %v", d.Input, d.Want, got)
+		}
+	}
+	// the read stops before the key is found again.
+	q, _ := Parse(".name")
+	err := StreamJSON(q, strings.NewReader(input), func(r Result) bool {
+		return false
+	})
+	if err != nil {
+		t.Errorf("stop: unexpected error: %s", err)
+	}
+}
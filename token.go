@@ -53,11 +53,22 @@ const (
 	TokSelectDatetime
 	TokSelectTruthy
 	TokSelectFalsy
+	TokSelectNull
+	TokNull
+	TokSelectKeys
+	TokSelectValues
+	TokSelectEntries
+	TokParent
+	TokAncestor
+	TokSibling
+	TokWildcard
+	TokGlobstar
 )
 
 var identifiers = map[string]rune{
 	"true":  TokBool,
 	"false": TokBool,
+	"null":  TokNull,
 	"inf":   TokFloat,
 	"+inf":  TokFloat,
 	"-inf":  TokFloat,
@@ -81,6 +92,25 @@ var selectors = map[string]rune{
 	"datetime": TokSelectDatetime,
 	"truthy":   TokSelectTruthy,
 	"falsy":    TokSelectFalsy,
+	"null":     TokSelectNull,
+	"keys":     TokSelectKeys,
+	"values":   TokSelectValues,
+	"entries":  TokSelectEntries,
+}
+
+var operators = map[rune]string{
+	TokEqual:      "==",
+	TokNotEqual:   "!=",
+	TokLesser:     "<",
+	TokLessEq:     "<=",
+	TokGreater:    ">",
+	TokGreatEq:    ">=",
+	TokStartsWith: "^=",
+	TokEndsWith:   "$=",
+	TokContains:   "*=",
+	TokMatch:      "~=",
+	TokAnd:        "&&",
+	TokOr:         "||",
 }
 
 var typenames = []struct {
@@ -92,10 +122,13 @@ var typenames = []struct {
 	{Label: "integer", Type: TokInteger, Compound: true},
 	{Label: "float", Type: TokFloat, Compound: true},
 	{Label: "boolean", Type: TokBool, Compound: true},
+	{Label: "null", Type: TokNull},
 	{Label: "date", Type: TokDate, Compound: true},
 	{Label: "time", Type: TokTime, Compound: true},
 	{Label: "datetime", Type: TokDateTime, Compound: true},
 	{Label: "pattern", Type: TokPattern, Compound: true},
+	{Label: "wildcard", Type: TokWildcard},
+	{Label: "globstar", Type: TokGlobstar},
 	{Label: "illegal", Type: TokIllegal, Compound: true},
 	{Label: ":at", Type: TokSelectAt},
 	{Label: ":range", Type: TokSelectRange},
@@ -111,6 +144,10 @@ var typenames = []struct {
 	{Label: ":datetime", Type: TokSelectDatetime},
 	{Label: ":truthy", Type: TokSelectTruthy},
 	{Label: ":falsy", Type: TokSelectFalsy},
+	{Label: ":null", Type: TokSelectNull},
+	{Label: ":keys", Type: TokSelectKeys},
+	{Label: ":values", Type: TokSelectValues},
+	{Label: ":entries", Type: TokSelectEntries},
 	{Label: "comma", Type: TokComma},
 	{Label: "and", Type: TokAnd},
 	{Label: "or", Type: TokOr},
@@ -129,6 +166,10 @@ var typenames = []struct {
 	{Label: "value", Type: TokValue},
 	{Label: "one", Type: TokLevelOne},
 	{Label: "any", Type: TokLevelAny},
+	{Label: "greedy", Type: TokLevelGreedy},
+	{Label: "parent", Type: TokParent},
+	{Label: "ancestor", Type: TokAncestor},
+	{Label: "sibling", Type: TokSibling},
 	{Label: "eof", Type: TokEOF},
 	{Label: "beg-expr", Type: TokBegExpr},
 	{Label: "end-expr", Type: TokEndExpr},
@@ -149,7 +190,7 @@ type Token struct {
 
 func (t Token) isValue() bool {
 	switch t.Type {
-	case TokLiteral, TokPattern, TokBool, TokInteger, TokFloat:
+	case TokLiteral, TokPattern, TokBool, TokInteger, TokFloat, TokNull:
 	case TokTime, TokDate, TokDateTime:
 	default:
 		return false
@@ -161,6 +202,10 @@ func (t Token) isKey() bool {
 	return t.Type == TokLiteral || t.Type == TokInteger || t.Type == TokPattern
 }
 
+func (t Token) isWildcard() bool {
+	return t.Type == TokWildcard || t.Type == TokGlobstar
+}
+
 func (t Token) isType() bool {
 	return t.Type == TokValue || t.Type == TokArray || t.Type == TokRegular
 }
@@ -174,11 +219,16 @@ func (t Token) isLevel() bool {
 	}
 }
 
+func (t Token) isAxis() bool {
+	return t.Type == TokParent || t.Type == TokAncestor || t.Type == TokSibling
+}
+
 func (t Token) isSelector() bool {
 	switch t.Type {
 	case TokSelectAt, TokSelectRange, TokSelectFirst, TokSelectLast:
 	case TokSelectInt, TokSelectFloat, TokSelectNumber, TokSelectBool, TokSelectString:
-	case TokSelectTruthy, TokSelectFalsy:
+	case TokSelectTruthy, TokSelectFalsy, TokSelectNull:
+	case TokSelectKeys, TokSelectValues, TokSelectEntries:
 	default:
 		return false
 	}
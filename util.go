@@ -1,14 +1,119 @@
 package query
 
 import (
+	"math"
+	"sort"
 	"time"
 )
 
-func asFloat(ifi interface{}) interface{} {
-	if i, ok := ifi.(int64); ok {
-		return float64(i)
+type Null struct{}
+
+func (_ Null) String() string {
+	return "null"
+}
+
+func isNull(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	_, ok := v.(Null)
+	return ok
+}
+
+func asFloat(ifi interface{}) (float64, bool) {
+	switch i := ifi.(type) {
+	case int64:
+		return float64(i), true
+	case float64:
+		return i, true
+	default:
+		return 0, false
+	}
+}
+
+func isNumber(ifi interface{}) bool {
+	switch ifi.(type) {
+	case int64, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareNumbers compares two numbers without losing precision when one is an
+// integer and the other a float. The returned bool is false when the numbers
+// can not be ordered (one of them is NaN).
+func compareNumbers(left, right interface{}) (int, bool) {
+	switch left := left.(type) {
+	case int64:
+		switch right := right.(type) {
+		case int64:
+			return compareInts(left, right), true
+		case float64:
+			return compareIntFloat(left, right)
+		}
+	case float64:
+		switch right := right.(type) {
+		case int64:
+			cmp, ok := compareIntFloat(right, left)
+			return -cmp, ok
+		case float64:
+			return compareFloats(left, right)
+		}
+	}
+	return 0, false
+}
+
+func compareInts(left, right int64) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloats(left, right float64) (int, bool) {
+	switch {
+	case math.IsNaN(left) || math.IsNaN(right):
+		return 0, false
+	case left < right:
+		return -1, true
+	case left > right:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+const (
+	minInt64Float = -(1 << 63)
+	maxInt64Float = 1 << 63
+)
+
+func compareIntFloat(left int64, right float64) (int, bool) {
+	switch {
+	case math.IsNaN(right):
+		return 0, false
+	case right >= maxInt64Float:
+		return -1, true
+	case right < minInt64Float:
+		return 1, true
+	}
+	trunc := math.Trunc(right)
+	if cmp := compareInts(left, int64(trunc)); cmp != 0 {
+		return cmp, true
+	}
+	switch {
+	case right > trunc:
+		return -1, true
+	case right < trunc:
+		return 1, true
+	default:
+		return 0, true
 	}
-	return ifi
 }
 
 func isArray(v interface{}) bool {
@@ -42,8 +147,19 @@ func isTrue(ifi interface{}) bool {
 		ok = len(i) > 0
 	case map[string]interface{}:
 		ok = len(i) > 0
+	case Null:
+		ok = false
 	default:
 		ok = ifi != nil
 	}
 	return ok
 }
+
+func sortedKeys(ifi map[string]interface{}) []string {
+	ks := make([]string, 0, len(ifi))
+	for k := range ifi {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
@@ -0,0 +1,186 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectWildcard(t *testing.T) {
+	data := []struct {
+		Input string
+		Paths []string
+	}{
+		{
+			Input: ".servers.*.addr",
+			Paths: []string{
+				"servers.backup.addr",
+				"servers.groups[0].addr",
+				"servers.groups[1].addr",
+				"servers.prime.addr",
+			},
+		},
+		{
+			Input: ".servers.$*.addr",
+			Paths: []string{"servers.backup.addr", "servers.prime.addr"},
+		},
+		{
+			Input: ".client.*[tls == true].addr",
+			Paths: []string{"client[1].addr", "client[2].addr"},
+		},
+		{
+			Input: ".admin.%*",
+			Paths: []string{"admin.dob", "admin.email", "admin.name"},
+		},
+		{
+			Input: ".@instances.*:int",
+			Paths: []string{"instances[0]", "instances[1]", "instances[2]"},
+		},
+		{
+			Input: ".**.user",
+			Paths: []string{"client[0].cred.user", "client[1].cred.user", "client[2].cred.user"},
+		},
+		{
+			Input: ".**[passwd == \"temp123!\"].user",
+			Paths: []string{"client[0].cred.user", "client[2].cred.user"},
+		},
+		{
+			Input: ".servers.**.mode",
+			Paths: []string{"servers.groups[0].mode", "servers.groups[1].mode"},
+		},
+		{
+			Input: ".client.**",
+			Paths: []string{"client[0]", "client[0].cred", "client[1]", "client[1].cred", "client[2]", "client[2].cred"},
+		},
+		{
+			Input: ".**.$*.user",
+			Paths: []string{"client[0].cred.user", "client[1].cred.user", "client[2].cred.user"},
+		},
+		{
+			Input: ".**.**.user",
+			Paths: []string{"client[0].cred.user", "client[1].cred.user", "client[2].cred.user"},
+		},
+		{
+			Input: ".**[tls == true].**.user",
+			Paths: []string{"client[1].cred.user", "client[2].cred.user"},
+		},
+		{
+			Input: ".**.cred[unknown == 1]",
+		},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		if str := q.(Node).String(); str != d.Input {
+			t.Errorf("%s: string mismatched! got %s", d.Input, str)
+		}
+		rs, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", d.Input, err)
+			continue
+		}
+		var paths []string
+		for _, r := range rs {
+			paths = append(paths, r.Paths.String())
+			if v, err := r.Paths.Resolve(doc); err != nil || !reflect.DeepEqual(v, r.Value) {
+				t.Errorf("%s: %s does not lead to %v", d.Input, r.Paths, r.Value)
+			}
+		}
+		if !reflect.DeepEqual(d.Paths, paths) {
+			t.Errorf("%s: paths mismatched! want %v, got %v", d.Input, d.Paths, paths)
+		}
+	}
+}
+
+func TestSelectWildcardSkip(t *testing.T) {
+	doc := map[string]interface{}{
+		"services": map[string]interface{}{
+			"default": "a",
+			"web": map[string]interface{}{
+				"port": int64(80),
+				"tls":  map[string]interface{}{"enabled": true},
+			},
+			"db": map[string]interface{}{
+				"port": int64(5432),
+				"tls":  map[string]interface{}{"cert": "db.pem"},
+			},
+			"cache": map[string]interface{}{
+				"port": int64(6379),
+				"tls":  map[string]interface{}{"enabled": false},
+			},
+		},
+	}
+	data := []struct {
+		Input string
+		Paths []string
+	}{
+		{
+			Input: ".services.*.port",
+			Paths: []string{"services.cache.port", "services.db.port", "services.web.port"},
+		},
+		{
+			Input: ".services.%*",
+			Paths: []string{"services.default"},
+		},
+		{
+			Input: ".services.%*.port",
+		},
+		{
+			Input: ".services.*[port > 1000]",
+			Paths: []string{"services.cache", "services.db"},
+		},
+		{
+			Input: ".services.%*^",
+			Paths: []string{"services"},
+		},
+		{
+			Input: ".**.tls[enabled == false]",
+			Paths: []string{"services.cache.tls"},
+		},
+		{
+			Input: ".**[enabled == true]",
+			Paths: []string{"services.web.tls"},
+		},
+	}
+	for _, d := range data {
+		q, err := Parse(d.Input)
+		if err != nil {
+			t.Errorf("%s: fail to parse: %s", d.Input, err)
+			continue
+		}
+		rs, err := q.Select(doc)
+		if err != nil {
+			t.Errorf("%s: fail to select: %s", d.Input, err)
+			continue
+		}
+		var paths []string
+		for _, r := range rs {
+			paths = append(paths, r.Paths.String())
+		}
+		if !reflect.DeepEqual(d.Paths, paths) {
+			t.Errorf("%s: paths mismatched! want %v, got %v", d.Input, d.Paths, paths)
+		}
+	}
+}
+
+func TestParseWildcardInvalid(t *testing.T) {
+	data := []string{
+		"***",
+		"$**",
+		"(foo,*)",
+		".foo*",
+		"*bar",
+		"*",
+		"..*",
+		"..**",
+		"..!*",
+		".foo..$*.bar",
+	}
+	for _, str := range data {
+		if _, err := Parse(str); err == nil {
+			t.Errorf("%q: expected error but query parsed", str)
+		}
+	}
+}